@@ -1,10 +1,12 @@
 package cfg
 
 import (
+	"context"
 	_ "embed"
 	"errors"
 	"fmt"
 	"os"
+	"reflect"
 	"sync"
 
 	"github.com/caarlos0/env/v10"
@@ -26,8 +28,13 @@ var (
 )
 
 type config struct {
-	prefix string
-	file   []byte
+	prefix       string
+	path         string
+	file         []byte
+	remote       map[string]string
+	remoteIndex  uint64
+	remoteSource *Remote
+	onDeprecated func(Deprecation)
 }
 
 type SetupParams struct {
@@ -37,6 +44,11 @@ type SetupParams struct {
 	ProdPath               string
 	LookupDepth            int
 	TargetEnvFileExtension string
+	// OnDeprecated is called once per deprecated key or env variable found while loading,
+	// repeated Load and Reload calls do not report it again
+	OnDeprecated func(Deprecation)
+	// Remote is an optional KV store layer merged between the YAML file and env
+	Remote *Remote
 }
 
 type configLoader struct {
@@ -59,6 +71,8 @@ func NewPrefix(p string) string {
 // - ProdPath: a string specifying the path to the production config file
 // - LookupDepth: an integer specifying the depth for file lookup
 // - TargetEnvFileExtension: a string specifying the extension of the target environment file
+// - OnDeprecated: an optional hook called for deprecated keys and env variables, e.g. to log a warning
+// - Remote: an optional KV store the config is pulled from, merged between the file and env
 //
 // It returns any error that occurred during the setup process.
 //
@@ -69,6 +83,9 @@ func NewPrefix(p string) string {
 //	    DevPath: "test.yaml",
 //	    LookupDepth: LookupDepthDefault,
 //	    TargetEnvFileExtension: ".env.test",
+//	    OnDeprecated: func(d Deprecation) {
+//	        logger.Warn(d.String())
+//	    },
 //	})
 func Setup(params *SetupParams) error {
 	loader := &configLoader{params: params}
@@ -80,16 +97,26 @@ func (c *configLoader) setupConfig() error {
 	c.loadEnvFile()
 	c.checkEnvVariable()
 
-	switch os.Getenv(c.params.Prefix + "ENV") {
+	if path := c.params.path(os.Getenv(c.params.Prefix + "ENV")); path != "" {
+		c.loadConfigFile(path)
+	}
+	c.loadRemote()
+
+	return c.err
+}
+
+// path returns the config file path of the environment or an empty string for an unknown one.
+func (p *SetupParams) path(envName string) string {
+	switch envName {
 	case devEnv:
-		c.loadConfigFile(c.params.DevPath)
+		return p.DevPath
 	case stageEnv:
-		c.loadConfigFile(c.params.StagePath)
+		return p.StagePath
 	case prodEnv:
-		c.loadConfigFile(c.params.ProdPath)
+		return p.ProdPath
 	}
 
-	return c.err
+	return ""
 }
 
 func (c *configLoader) setDefaultValues() {
@@ -125,13 +152,26 @@ func (c *configLoader) loadConfigFile(filePath string) {
 			return
 		}
 		c.cfg = &config{
-			prefix: c.params.Prefix,
-			file:   file,
+			prefix:       c.params.Prefix,
+			path:         filePath,
+			file:         file,
+			onDeprecated: warnOnce(c.params.OnDeprecated),
 		}
 		cfg = c.cfg
 	}
 }
 
+func (c *configLoader) loadRemote() {
+	if c.err == nil && c.params.Remote != nil && c.cfg != nil {
+		pairs, index, err := c.params.Remote.Fetch(context.Background())
+		if err != nil {
+			c.err = fmt.Errorf("unable to fetch remote config: %w", err)
+			return
+		}
+		c.cfg.remote, c.cfg.remoteIndex, c.cfg.remoteSource = pairs, index, c.params.Remote
+	}
+}
+
 // Load is a function that loads the configuration data into the destination struct.
 //
 // Example usage:
@@ -145,13 +185,83 @@ func Load(dst interface{}) error {
 	if cfg == nil {
 		return fmt.Errorf("config is nil, check setup")
 	}
-	if err := yaml.Unmarshal(cfg.file, dst); err != nil {
+
+	return LoadWith(dst, LoadParams{
+		File:         cfg.file,
+		Path:         cfg.path,
+		Remote:       cfg.remote,
+		Prefix:       cfg.prefix,
+		OnDeprecated: cfg.onDeprecated,
+	})
+}
+
+// LoadParams describes a config document and the environment it is decoded against.
+// File is the raw YAML document and Path is where it was read from, includes are
+// resolved relative to it (or to the working directory if it is empty).
+// Prefix is the environment variables prefix and Environment replaces
+// the process environment when it is not nil. Either way a variable set to an empty string
+// does not override the YAML value, the same way env.Parse treats the process environment.
+// Remote holds KV store pairs (see Remote) merged between the file and env layers,
+// RemoteIndex is the store index they were fetched at, a Reloader watches for changes after it.
+// OnDeprecated is called for every deprecated key or env variable in use on every call.
+type LoadParams struct {
+	File         []byte
+	Path         string
+	Remote       map[string]string
+	RemoteIndex  uint64
+	Prefix       string
+	Environment  map[string]string
+	OnDeprecated func(Deprecation)
+}
+
+// LoadWith runs the same pipeline as Load (YAML, then env, then validation)
+// against the provided params instead of the global config set up by Setup.
+// It does not read or modify any process state when params.Environment is set.
+func LoadWith(dst interface{}, params LoadParams) error {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(params.File, &doc); err != nil {
+		return fmt.Errorf("unmarshal error: %w", err)
+	}
+
+	root := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	if len(doc.Content) > 0 {
+		root = doc.Content[0]
+		if err := resolveIncludes(root, params.Path); err != nil {
+			return fmt.Errorf("include error: %w", err)
+		}
+		if err := applyMigrations(root); err != nil {
+			return fmt.Errorf("migrate error: %w", err)
+		}
+	}
+
+	if len(params.Remote) > 0 {
+		remote, err := remoteNode(params.Remote)
+		if err != nil {
+			return fmt.Errorf("remote error: %w", err)
+		}
+		merge(root, remote)
+	}
+
+	if err := resolveDeprecatedKeys(root, reflect.TypeOf(dst), params.OnDeprecated); err != nil {
+		return fmt.Errorf("deprecated keys error: %w", err)
+	}
+	if err := root.Decode(dst); err != nil {
 		return fmt.Errorf("unmarshal error: %w", err)
 	}
 
+	environment := params.Environment
+	if environment == nil {
+		environment = processEnviron()
+	}
+	environment, err := resolveDeprecatedEnv(environment, reflect.TypeOf(dst), params.Prefix, params.OnDeprecated)
+	if err != nil {
+		return fmt.Errorf("deprecated env error: %w", err)
+	}
+
 	opts := env.Options{
+		Environment:           environment,
 		UseFieldNameByDefault: false,
-		Prefix:                cfg.prefix,
+		Prefix:                params.Prefix,
 	}
 
 	if err := env.ParseWithOptions(dst, opts); err != nil {
@@ -161,6 +271,53 @@ func Load(dst interface{}) error {
 	return validateConfig(dst)
 }
 
+// ParamsFor returns LoadParams for the config file of the environment (dev, stage or prod)
+// without calling Setup. The environment is the process one with the .env file
+// found by params added on top and ENV set to envName, so several environments
+// can be loaded side by side in one process.
+func ParamsFor(params *SetupParams, envName string) (LoadParams, error) {
+	path := params.path(envName)
+	if path == "" {
+		return LoadParams{}, fmt.Errorf("no config file for %q environment", envName)
+	}
+
+	return ParamsForFile(params, path, envName)
+}
+
+// ParamsForFile is like ParamsFor but reads the config from the provided file.
+func ParamsForFile(params *SetupParams, path, envName string) (LoadParams, error) {
+	file, err := os.ReadFile(path)
+	if err != nil {
+		return LoadParams{}, fmt.Errorf("unable to read %s file: %w", path, err)
+	}
+
+	depth := params.LookupDepth
+	if depth == 0 {
+		depth = LookupDepthDefault
+	}
+
+	var envFile string
+	if params.TargetEnvFileExtension != "" {
+		envFile, _ = NewLookup(params.TargetEnvFileExtension, depth).FindFile()
+	}
+
+	environment, err := environ(envFile)
+	if err != nil {
+		return LoadParams{}, fmt.Errorf("load %s file error: %w", envFile, err)
+	}
+	if envName != "" {
+		environment[params.Prefix+"ENV"] = envName
+	}
+
+	return LoadParams{
+		File:         file,
+		Path:         path,
+		Prefix:       params.Prefix,
+		Environment:  environment,
+		OnDeprecated: params.OnDeprecated,
+	}, nil
+}
+
 func validateConfig(dst interface{}) error {
 	if err := validate.Struct(dst); err != nil {
 		return accumulateError(err)
@@ -40,9 +40,7 @@ func TestCfg(t *testing.T) {
 		LookupDepth:            LookupDepthDefault,
 		TargetEnvFileExtension: ".env.test",
 	})
-	if err != nil {
-		return
-	}
+	require.NoError(t, err)
 
 	t.Run("testYaml", func(t *testing.T) {
 		testYaml(t)
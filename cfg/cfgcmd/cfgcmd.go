@@ -0,0 +1,108 @@
+// Package cfgcmd contains command line tools working on top of the cfg pipeline.
+// The tools do not know the config type, so a service registers it in a tiny main,
+// e.g. cmd/cfgcheck/main.go (see cfg/cmd/cfgcheck, checking the sections defined by this module):
+//
+//	func main() {
+//	    os.Exit(cfgcmd.Check(os.Args[1:], func() interface{} { return &config.Config{} }, os.Stdout, os.Stderr))
+//	}
+//
+// and runs it in CI:
+//
+//	go run ./cmd/cfgcheck -dev cfg/dev.yaml -stage cfg/stage.yaml -prod cfg/prod.yaml -env-file .env.sample
+package cfgcmd
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/sorohimm/utils/cfg"
+)
+
+// Exit codes: ExitOK, ExitChanged and ExitError follow diff(1) (no differences, differences found,
+// trouble), ExitInvalid is returned by Check for configs failing to load or validate, so CI tells
+// a changed config from a broken one.
+const (
+	ExitOK      = 0
+	ExitChanged = 1
+	ExitError   = 2
+	ExitInvalid = 3
+)
+
+// setupFlags registers the flags describing cfg.SetupParams on fs.
+func setupFlags(fs *flag.FlagSet) *cfg.SetupParams {
+	params := &cfg.SetupParams{}
+	fs.StringVar(&params.Prefix, "prefix", "", "environment variables prefix")
+	fs.StringVar(&params.DevPath, "dev", "", "dev config file path")
+	fs.StringVar(&params.StagePath, "stage", "", "stage config file path")
+	fs.StringVar(&params.ProdPath, "prod", "", "prod config file path")
+	fs.StringVar(&params.TargetEnvFileExtension, "env-file", "", "name of the .env file to look up")
+	fs.IntVar(&params.LookupDepth, "depth", cfg.LookupDepthDefault, "env file lookup depth")
+
+	return params
+}
+
+// resolve turns an environment name or a file path into load params.
+// A path is loaded with ENV set to the environment given by the optional "env:path" form.
+func resolve(params *cfg.SetupParams, target string) (cfg.LoadParams, error) {
+	switch target {
+	case "dev", "stage", "prod":
+		return cfg.ParamsFor(params, target)
+	}
+
+	envName := ""
+	for _, env := range []string{"dev", "stage", "prod"} {
+		if path, ok := strings.CutPrefix(target, env+":"); ok {
+			envName, target = env, path
+			break
+		}
+	}
+
+	return cfg.ParamsForFile(params, target, envName)
+}
+
+// Diff loads two configs and prints their field-level difference with secrets masked.
+// Each argument is an environment name (dev, stage, prod), a file path,
+// or an "env:path" pair to load a new revision of an environment file:
+//
+//	cfgdiff -stage stage.yaml -prod prod.yaml stage prod
+//	cfgdiff -prod prod.yaml prod prod:prod.next.yaml
+func Diff(args []string, newDst func() interface{}, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("cfgdiff", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	params := setupFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return ExitError
+	}
+	if fs.NArg() != 2 {
+		_, _ = fmt.Fprintln(stderr, "usage: cfgdiff [flags] <left> <right>")
+		fs.PrintDefaults()
+		return ExitError
+	}
+
+	left, err := resolve(params, fs.Arg(0))
+	if err != nil {
+		_, _ = fmt.Fprintln(stderr, err)
+		return ExitError
+	}
+	right, err := resolve(params, fs.Arg(1))
+	if err != nil {
+		_, _ = fmt.Fprintln(stderr, err)
+		return ExitError
+	}
+
+	report, err := cfg.DiffLoad(newDst, left, right)
+	if err != nil {
+		_, _ = fmt.Fprintln(stderr, err)
+		return ExitError
+	}
+
+	_, _ = fmt.Fprintf(stdout, "--- %s\n+++ %s\n", fs.Arg(0), fs.Arg(1))
+	if len(report.Changes) == 0 {
+		return ExitOK
+	}
+	_, _ = fmt.Fprintln(stdout, report.String())
+
+	return ExitChanged
+}
@@ -0,0 +1,79 @@
+package cfgcmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/sorohimm/utils/cfg"
+)
+
+type testCfg struct {
+	Port     int            `yaml:"port" validate:"required"`
+	Password cfg.SafeString `yaml:"password"`
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+	return path
+}
+
+func newCfg() interface{} {
+	return &testCfg{}
+}
+
+func TestDiff(t *testing.T) {
+	stage := writeFile(t, "stage.yaml", "port: 8000\npassword: stage-password\n")
+	prod := writeFile(t, "prod.yaml", "port: 443\npassword: stage-password\n")
+
+	var stdout, stderr bytes.Buffer
+	code := Diff([]string{"-stage", stage, "-prod", prod, "stage", "prod"}, newCfg, &stdout, &stderr)
+	require.Equal(t, ExitChanged, code, stderr.String())
+	require.Equal(t, "--- stage\n+++ prod\n~ port: 8000 -> 443\n", stdout.String())
+
+	stdout.Reset()
+	code = Diff([]string{"-prod", prod, "prod", "prod:" + prod}, newCfg, &stdout, &stderr)
+	require.Equal(t, ExitOK, code, stderr.String())
+
+	code = Diff([]string{"stage"}, newCfg, &stdout, &stderr)
+	require.Equal(t, ExitError, code)
+}
+
+func TestCheck(t *testing.T) {
+	dev := writeFile(t, "dev.yaml", "port: 8000\n")
+	prod := writeFile(t, "prod.yaml", "password: prod-password\n")
+
+	var stdout, stderr bytes.Buffer
+	code := Check([]string{"-dev", dev, "-prod", prod, "-env-file", ".env.test"}, newCfg, &stdout, &stderr)
+	require.Equal(t, ExitInvalid, code, stderr.String())
+	require.Equal(t, "dev: ok\nprod: FAIL\n"+
+		"  Key: 'testCfg.Port' Error:Field validation for 'Port' failed on the 'required' tag\n", stdout.String())
+
+	stdout.Reset()
+	code = Check([]string{"-dev", dev, "-prod", prod, "-env-file", ".env.test", "-json", "-envs", "prod"}, newCfg, &stdout, &stderr)
+	require.Equal(t, ExitInvalid, code)
+	require.JSONEq(t, `[{"env":"prod","errors":[{"field":"Port","tag":"required",
+		"message":"Key: 'testCfg.Port' Error:Field validation for 'Port' failed on the 'required' tag"}]}]`,
+		stdout.String())
+
+	stdout.Reset()
+	broken := writeFile(t, "stage.yaml", "port: [\n")
+	code = Check([]string{"-stage", broken, "-env-file", ".env.test"}, newCfg, &stdout, &stderr)
+	require.Equal(t, ExitInvalid, code)
+	require.Contains(t, stdout.String(), "stage: FAIL\n  unmarshal error")
+
+	stdout.Reset()
+	code = Check([]string{"-dev", dev, "-env-file", ".env.test", "-envs", "dev,prod"}, newCfg, &stdout, &stderr)
+	require.Equal(t, ExitInvalid, code)
+	require.Equal(t, "dev: ok\nprod: FAIL\n  no config file for \"prod\" environment\n", stdout.String())
+	require.Empty(t, os.Getenv("ENV"))
+
+	code = Check(nil, newCfg, &stdout, &stderr)
+	require.Equal(t, ExitError, code)
+}
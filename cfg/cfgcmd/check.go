@@ -0,0 +1,144 @@
+package cfgcmd
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/sorohimm/utils/cfg"
+)
+
+// CheckResult is the outcome of loading the config of a single environment.
+type CheckResult struct {
+	Env    string           `json:"env"`
+	Errors []cfg.FieldError `json:"errors,omitempty"`
+}
+
+// Valid reports whether the config loaded and passed validation.
+func (r CheckResult) Valid() bool {
+	return len(r.Errors) == 0
+}
+
+// Check loads the config of every environment with a file set (or the ones listed by -envs)
+// into a value produced by newDst through the same cfg.Setup and cfg.Load pipeline a service runs,
+// and prints the validation errors. It returns ExitInvalid if any config fails to load or validate.
+// Setup changes the process state (the global config and the variables of the .env file),
+// so Check is meant to run in its own process.
+//
+//	cfgcheck -dev dev.yaml -prod prod.yaml -env-file .env.sample [-json] [-envs prod]
+func Check(args []string, newDst func() interface{}, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("cfgcheck", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	params := setupFlags(fs)
+	envs := fs.String("envs", "", "comma-separated environments to check, all with a file set by default")
+	asJSON := fs.Bool("json", false, "print results as JSON")
+	if err := fs.Parse(args); err != nil {
+		return ExitError
+	}
+
+	names := checkedEnvs(params, *envs)
+	if len(names) == 0 {
+		_, _ = fmt.Fprintln(stderr, "no environments to check, set -dev, -stage or -prod")
+		return ExitError
+	}
+
+	code := ExitOK
+	results := make([]CheckResult, 0, len(names))
+	for _, env := range names {
+		r := check(params, env, newDst)
+		if !r.Valid() {
+			code = ExitInvalid
+		}
+		results = append(results, r)
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(results)
+		return code
+	}
+
+	for _, r := range results {
+		if r.Valid() {
+			_, _ = fmt.Fprintf(stdout, "%s: ok\n", r.Env)
+			continue
+		}
+		_, _ = fmt.Fprintf(stdout, "%s: FAIL\n", r.Env)
+		for _, e := range r.Errors {
+			_, _ = fmt.Fprintf(stdout, "  %s\n", e.Message)
+		}
+	}
+
+	return code
+}
+
+func checkedEnvs(params *cfg.SetupParams, envs string) []string {
+	if envs != "" {
+		return strings.Split(envs, ",")
+	}
+
+	var res []string
+	for _, e := range []struct{ env, path string }{
+		{"dev", params.DevPath},
+		{"stage", params.StagePath},
+		{"prod", params.ProdPath},
+	} {
+		if e.path != "" {
+			res = append(res, e.env)
+		}
+	}
+
+	return res
+}
+
+// check loads the config of the environment the way a service does: cfg.Setup with ENV
+// set to the environment (the .env file, includes, migrations, deprecated keys, remote)
+// and then cfg.Load (env and validation).
+func check(params *cfg.SetupParams, env string, newDst func() interface{}) CheckResult {
+	r := CheckResult{Env: env}
+
+	err := setupEnv(params, env)
+	if err == nil {
+		err = cfg.Load(newDst())
+	}
+	if err == nil {
+		return r
+	}
+
+	var cfgErr cfg.Error
+	if errors.As(err, &cfgErr) && len(cfgErr.Fields()) > 0 {
+		r.Errors = cfgErr.Fields()
+		return r
+	}
+	r.Errors = []cfg.FieldError{{Message: err.Error()}}
+
+	return r
+}
+
+// setupEnv runs cfg.Setup for the environment, restoring the ENV variable afterwards.
+func setupEnv(params *cfg.SetupParams, env string) error {
+	paths := map[string]string{"dev": params.DevPath, "stage": params.StagePath, "prod": params.ProdPath}
+	if paths[env] == "" {
+		return fmt.Errorf("no config file for %q environment", env)
+	}
+
+	key := params.Prefix + "ENV"
+	prev, ok := os.LookupEnv(key)
+	defer func() {
+		if ok {
+			_ = os.Setenv(key, prev)
+			return
+		}
+		_ = os.Unsetenv(key)
+	}()
+	if err := os.Setenv(key, env); err != nil {
+		return fmt.Errorf("set %s error: %w", key, err)
+	}
+
+	return cfg.Setup(params)
+}
@@ -0,0 +1,176 @@
+// Package cfgtest contains helpers for testing config structs loaded with the cfg package
+// without writing real files or touching the process environment.
+package cfgtest
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sorohimm/utils/cfg"
+)
+
+var update = flag.Bool("cfgtest.update", false, "rewrite cfgtest golden files")
+
+const goldenDir = "testdata"
+
+// Load decodes the inline YAML document and the env map into dst through cfg.LoadWith
+// and fails the test on any error. A nil env map is treated as an empty environment.
+func Load(t testing.TB, dst interface{}, doc string, env map[string]string) {
+	t.Helper()
+	require.NoError(t, load(dst, doc, env))
+}
+
+// LoadError is like Load but expects the pipeline to fail and returns the error.
+func LoadError(t testing.TB, dst interface{}, doc string, env map[string]string) error {
+	t.Helper()
+	err := load(dst, doc, env)
+	require.Error(t, err)
+
+	return err
+}
+
+func load(dst interface{}, doc string, env map[string]string) error {
+	if env == nil {
+		env = map[string]string{}
+	}
+
+	return cfg.LoadWith(dst, cfg.LoadParams{
+		File:        []byte(Dedent(doc)),
+		Environment: env,
+	})
+}
+
+// AssertInvalid checks that err contains a validation failure for the field with the given tag.
+// The field is matched against the struct field name or the full namespace (e.g. "Cfg.Http.URL").
+func AssertInvalid(t testing.TB, err error, field, tag string) {
+	t.Helper()
+	require.Error(t, err)
+
+	var got []string
+	for _, fe := range fieldErrors(err) {
+		if fe.Tag() == tag && (fe.Field() == field || fe.Namespace() == field) {
+			return
+		}
+		got = append(got, fe.Namespace()+":"+fe.Tag())
+	}
+
+	require.Failf(t, "validation failure not found",
+		"want %s:%s, got %v (%v)", field, tag, got, err)
+}
+
+func fieldErrors(err error) []validator.FieldError {
+	var cfgErr cfg.Error
+	if !errors.As(err, &cfgErr) {
+		return nil
+	}
+
+	var res []validator.FieldError
+	for _, e := range cfgErr.Unwrap() {
+		var fe validator.FieldError
+		if errors.As(e, &fe) {
+			res = append(res, fe)
+		}
+	}
+
+	return res
+}
+
+// AssertGolden compares the masked JSON form of v with testdata/<name>.golden.
+// Secrets are masked by their MarshalJSON methods (see cfg.SafeString).
+// Run tests with -cfgtest.update to rewrite the golden file.
+func AssertGolden(t testing.TB, name string, v interface{}) {
+	t.Helper()
+
+	got, err := json.MarshalIndent(v, "", "  ")
+	require.NoError(t, err)
+	got = append(got, '\n')
+
+	path := filepath.Join(goldenDir, name+".golden")
+	if *update {
+		require.NoError(t, os.MkdirAll(goldenDir, 0o755))
+		require.NoError(t, os.WriteFile(path, got, 0o644))
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	require.NoError(t, err, "run tests with -cfgtest.update to create golden files")
+	require.Equal(t, string(want), string(got))
+}
+
+// Environments loads the config file of every environment set in params into a value
+// produced by newDst and compares it with the testdata/<env>.golden snapshot.
+// The env map is shared by all environments, the ENV variable is set per environment.
+// Includes are resolved relative to the config file of the environment.
+func Environments(t *testing.T, params *cfg.SetupParams, newDst func() interface{}, env map[string]string) {
+	t.Helper()
+
+	envs := []struct {
+		name string
+		path string
+	}{
+		{"dev", params.DevPath},
+		{"stage", params.StagePath},
+		{"prod", params.ProdPath},
+	}
+
+	for _, e := range envs {
+		if e.path == "" {
+			continue
+		}
+		e := e
+		t.Run(e.name, func(t *testing.T) {
+			file, err := os.ReadFile(e.path)
+			require.NoError(t, err)
+
+			environment := map[string]string{params.Prefix + "ENV": e.name}
+			for k, v := range env {
+				environment[k] = v
+			}
+
+			dst := newDst()
+			require.NoError(t, cfg.LoadWith(dst, cfg.LoadParams{
+				File:        file,
+				Path:        e.path,
+				Prefix:      params.Prefix,
+				Environment: environment,
+			}))
+			AssertGolden(t, e.name, dst)
+		})
+	}
+}
+
+// Dedent removes the common leading whitespace of all non-empty lines,
+// so YAML documents can be indented with tabs inside Go raw string literals.
+func Dedent(s string) string {
+	lines := strings.Split(s, "\n")
+
+	prefix := ""
+	first := true
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+		if first {
+			prefix, first = indent, false
+			continue
+		}
+		for !strings.HasPrefix(line, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+
+	for i, line := range lines {
+		lines[i] = strings.TrimPrefix(line, prefix)
+	}
+
+	return strings.TrimSpace(strings.Join(lines, "\n")) + "\n"
+}
@@ -0,0 +1,54 @@
+package cfgtest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/sorohimm/utils/cfg"
+)
+
+type testCfg struct {
+	Http struct {
+		URL  string `yaml:"url" env:"HTTP_URL" validate:"required,url"`
+		Port int    `yaml:"port" validate:"required"`
+	} `yaml:"http"`
+	Password cfg.SafeString `yaml:"password" env:"PASSWORD"`
+}
+
+func TestLoad(t *testing.T) {
+	c := testCfg{}
+	Load(t, &c, `
+		http:
+		  url: https://example.com
+		  port: 8000
+	`, map[string]string{"HTTP_URL": "http://a.b.c"})
+
+	require.Equal(t, "http://a.b.c", c.Http.URL)
+	require.Equal(t, 8000, c.Http.Port)
+}
+
+func TestAssertInvalid(t *testing.T) {
+	c := testCfg{}
+	err := LoadError(t, &c, `
+		http:
+		  url: not a url
+	`, nil)
+
+	AssertInvalid(t, err, "URL", "url")
+	AssertInvalid(t, err, "testCfg.Http.Port", "required")
+}
+
+func TestEnvironments(t *testing.T) {
+	Environments(t, &cfg.SetupParams{
+		Prefix:   "APP_",
+		DevPath:  "testdata/dev.yaml",
+		ProdPath: "testdata/prod.yaml",
+	}, func() interface{} {
+		return &testCfg{}
+	}, map[string]string{"APP_PASSWORD": "very-secret-password"})
+}
+
+func TestDedent(t *testing.T) {
+	require.Equal(t, "a:\n  b: 1\n", Dedent("\n\t\ta:\n\t\t  b: 1\n\t"))
+}
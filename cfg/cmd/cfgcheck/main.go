@@ -0,0 +1,30 @@
+// Command cfgcheck validates the config of every environment through the cfg.Setup and cfg.Load
+// pipeline and exits with cfgcmd.ExitInvalid if any of them is broken:
+//
+//	go run github.com/sorohimm/utils/cfg/cmd/cfgcheck -dev cfg/dev.yaml -prod cfg/prod.yaml -env-file .env.sample
+//
+// It checks the sections defined by this module, other keys are ignored. To check the whole
+// service config, copy this main into the service and return its config type from newConfig.
+package main
+
+import (
+	"os"
+
+	"github.com/sorohimm/utils/cfg/cfgcmd"
+	"github.com/sorohimm/utils/cfg/flags"
+	"github.com/sorohimm/utils/log"
+)
+
+// Config holds the config sections defined by this module.
+type Config struct {
+	Log          log.Config `yaml:"log" envPrefix:"LOG_"`
+	flags.Config `yaml:",inline"`
+}
+
+func newConfig() interface{} {
+	return &Config{}
+}
+
+func main() {
+	os.Exit(cfgcmd.Check(os.Args[1:], newConfig, os.Stdout, os.Stderr))
+}
@@ -0,0 +1,268 @@
+package cfg
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// deprecatedTag lists old YAML keys of a field, e.g. `yaml:"timeout" deprecated:"idleTimeout,httpTimeout"`
+	deprecatedTag = "deprecated"
+	// envDeprecatedTag lists old env variables of a field, e.g. `env:"TIMEOUT" envDeprecated:"IDLE_TIMEOUT"`
+	envDeprecatedTag = "envDeprecated"
+)
+
+// Deprecation describes a deprecated YAML key or env variable found while loading.
+// Line is the YAML line of the key and is zero for env variables.
+type Deprecation struct {
+	Old  string
+	New  string
+	Env  bool
+	Line int
+}
+
+// warnOnce returns fn calling it once per deprecated key or env variable.
+func warnOnce(fn func(Deprecation)) func(Deprecation) {
+	if fn == nil {
+		return nil
+	}
+
+	var seen sync.Map
+
+	return func(d Deprecation) {
+		if _, loaded := seen.LoadOrStore(fmt.Sprintf("%t:%s", d.Env, d.Old), struct{}{}); !loaded {
+			fn(d)
+		}
+	}
+}
+
+func (d Deprecation) String() string {
+	if d.Env {
+		return fmt.Sprintf("env variable %s is deprecated, use %s", d.Old, d.New)
+	}
+
+	return fmt.Sprintf("line %d: config key %q is deprecated, use %q", d.Line, d.Old, d.New)
+}
+
+// resolveDeprecatedKeys renames deprecated keys of the document to the keys of the dst type.
+// It fails if both an old and a new key are set to different values.
+func resolveDeprecatedKeys(node *yaml.Node, t reflect.Type, hook func(Deprecation)) error {
+	t = indirect(t)
+
+	switch {
+	case node.Kind == yaml.MappingNode && t.Kind() == reflect.Struct:
+		return resolveStructKeys(node, t, hook)
+	case node.Kind == yaml.MappingNode && t.Kind() == reflect.Map:
+		for i := 1; i < len(node.Content); i += 2 {
+			if err := resolveDeprecatedKeys(node.Content[i], t.Elem(), hook); err != nil {
+				return err
+			}
+		}
+	case node.Kind == yaml.SequenceNode && (t.Kind() == reflect.Slice || t.Kind() == reflect.Array):
+		for _, item := range node.Content {
+			if err := resolveDeprecatedKeys(item, t.Elem(), hook); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func resolveStructKeys(node *yaml.Node, t reflect.Type, hook func(Deprecation)) error {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, inline := yamlName(f)
+		if name == "-" {
+			continue
+		}
+		if inline {
+			if indirect(f.Type).Kind() == reflect.Struct {
+				if err := resolveStructKeys(node, indirect(f.Type), hook); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		for _, old := range splitTag(f.Tag.Get(deprecatedTag)) {
+			if err := renameKey(node, old, name, hook); err != nil {
+				return err
+			}
+		}
+
+		if idx := keyIndex(node, name); idx >= 0 {
+			if err := resolveDeprecatedKeys(node.Content[idx+1], f.Type, hook); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func renameKey(node *yaml.Node, old, name string, hook func(Deprecation)) error {
+	oldIdx := keyIndex(node, old)
+	if oldIdx < 0 {
+		return nil
+	}
+
+	oldKey := node.Content[oldIdx]
+	if hook != nil {
+		hook(Deprecation{Old: old, New: name, Line: oldKey.Line})
+	}
+
+	newIdx := keyIndex(node, name)
+	if newIdx < 0 {
+		oldKey.Value = name
+		return nil
+	}
+
+	same, err := sameValue(node.Content[oldIdx+1], node.Content[newIdx+1])
+	if err != nil {
+		return err
+	}
+	if !same {
+		return fmt.Errorf("line %d: deprecated key %q conflicts with %q set at line %d",
+			oldKey.Line, old, name, node.Content[newIdx].Line)
+	}
+	node.Content = append(node.Content[:oldIdx], node.Content[oldIdx+2:]...)
+
+	return nil
+}
+
+// keyIndex returns the index of the key node in the mapping node content or -1.
+func keyIndex(node *yaml.Node, key string) int {
+	if node.Kind != yaml.MappingNode {
+		return -1
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func sameValue(a, b *yaml.Node) (bool, error) {
+	var av, bv interface{}
+	if err := a.Decode(&av); err != nil {
+		return false, fmt.Errorf("line %d: %w", a.Line, err)
+	}
+	if err := b.Decode(&bv); err != nil {
+		return false, fmt.Errorf("line %d: %w", b.Line, err)
+	}
+
+	return reflect.DeepEqual(av, bv), nil
+}
+
+// resolveDeprecatedEnv returns the environment with values of deprecated env variables
+// copied to the variables of the dst type. The provided map is not modified.
+// It fails if both an old and a new variable are set to different values.
+func resolveDeprecatedEnv(environment map[string]string, t reflect.Type, prefix string,
+	hook func(Deprecation)) (map[string]string, error) {
+	r := &envResolver{environment: environment, hook: hook, seen: map[reflect.Type]bool{}}
+	if err := r.resolve(indirect(t), prefix); err != nil {
+		return nil, err
+	}
+
+	return r.environment, nil
+}
+
+type envResolver struct {
+	environment map[string]string
+	copied      bool
+	hook        func(Deprecation)
+	seen        map[reflect.Type]bool
+}
+
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+func (r *envResolver) resolve(t reflect.Type, prefix string) error {
+	if t.Kind() != reflect.Struct || r.seen[t] {
+		return nil
+	}
+	r.seen[t] = true
+	defer delete(r.seen, t)
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+
+		name, _, _ := strings.Cut(f.Tag.Get("env"), ",")
+		if name != "" {
+			for _, old := range splitTag(f.Tag.Get(envDeprecatedTag)) {
+				if err := r.rename(prefix+old, prefix+name); err != nil {
+					return err
+				}
+			}
+		}
+
+		ft := indirect(f.Type)
+		if ft.Kind() == reflect.Struct && !reflect.PointerTo(ft).Implements(textUnmarshalerType) {
+			if err := r.resolve(ft, prefix+f.Tag.Get("envPrefix")); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (r *envResolver) rename(old, name string) error {
+	value := r.environment[old]
+	if value == "" {
+		return nil
+	}
+	if r.hook != nil {
+		r.hook(Deprecation{Old: old, New: name, Env: true})
+	}
+
+	if current := r.environment[name]; current != "" {
+		if current != value {
+			return fmt.Errorf("deprecated env variable %s conflicts with %s", old, name)
+		}
+		return nil
+	}
+
+	if !r.copied {
+		environment := make(map[string]string, len(r.environment)+1)
+		for k, v := range r.environment {
+			environment[k] = v
+		}
+		r.environment, r.copied = environment, true
+	}
+	r.environment[name] = value
+
+	return nil
+}
+
+func indirect(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	return t
+}
+
+func splitTag(tag string) []string {
+	var res []string
+	for _, s := range strings.Split(tag, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			res = append(res, s)
+		}
+	}
+
+	return res
+}
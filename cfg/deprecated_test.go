@@ -0,0 +1,121 @@
+package cfg
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+type deprecatedCfg struct {
+	Http struct {
+		Timeout string `yaml:"timeout" env:"TIMEOUT" deprecated:"idleTimeout,httpTimeout" envDeprecated:"IDLE_TIMEOUT"`
+	} `yaml:"http" envPrefix:"HTTP_"`
+	Hosts []struct {
+		Name string `yaml:"name" deprecated:"host"`
+	} `yaml:"hosts"`
+}
+
+func TestDeprecated(t *testing.T) {
+	t.Run("testDeprecatedKeys", testDeprecatedKeys)
+	t.Run("testDeprecatedConflict", testDeprecatedConflict)
+	t.Run("testDeprecatedEnv", testDeprecatedEnv)
+	t.Run("testDeprecatedWarnOnce", testDeprecatedWarnOnce)
+}
+
+func testDeprecatedKeys(t *testing.T) {
+	var got []Deprecation
+	c := deprecatedCfg{}
+	err := LoadWith(&c, LoadParams{
+		File:         []byte("http:\n  idleTimeout: 5s\nhosts:\n  - host: a\n  - name: b\n"),
+		Environment:  map[string]string{},
+		OnDeprecated: func(d Deprecation) { got = append(got, d) },
+	})
+	require.NoError(t, err)
+	require.Equal(t, "5s", c.Http.Timeout)
+	require.Equal(t, "a", c.Hosts[0].Name)
+	require.Equal(t, "b", c.Hosts[1].Name)
+	require.Equal(t, []Deprecation{
+		{Old: "idleTimeout", New: "timeout", Line: 2},
+		{Old: "host", New: "name", Line: 4},
+	}, got)
+}
+
+func testDeprecatedConflict(t *testing.T) {
+	c := deprecatedCfg{}
+	err := LoadWith(&c, LoadParams{
+		File:        []byte("http:\n  timeout: 5s\n  httpTimeout: 5s\n"),
+		Environment: map[string]string{},
+	})
+	require.NoError(t, err)
+
+	err = LoadWith(&c, LoadParams{
+		File:        []byte("http:\n  timeout: 5s\n  httpTimeout: 10s\n"),
+		Environment: map[string]string{},
+	})
+	require.ErrorContains(t, err, `line 3: deprecated key "httpTimeout" conflicts with "timeout" set at line 2`)
+}
+
+func testDeprecatedEnv(t *testing.T) {
+	var got []Deprecation
+	environment := map[string]string{"APP_HTTP_IDLE_TIMEOUT": "7s"}
+	c := deprecatedCfg{}
+	err := LoadWith(&c, LoadParams{
+		Prefix:       "APP_",
+		Environment:  environment,
+		OnDeprecated: func(d Deprecation) { got = append(got, d) },
+	})
+	require.NoError(t, err)
+	require.Equal(t, "7s", c.Http.Timeout)
+	require.Equal(t, []Deprecation{{Old: "APP_HTTP_IDLE_TIMEOUT", New: "APP_HTTP_TIMEOUT", Env: true}}, got)
+	require.Len(t, environment, 1)
+
+	environment["APP_HTTP_TIMEOUT"] = "8s"
+	err = LoadWith(&c, LoadParams{Prefix: "APP_", Environment: environment})
+	require.ErrorContains(t, err, "deprecated env variable APP_HTTP_IDLE_TIMEOUT conflicts with APP_HTTP_TIMEOUT")
+}
+
+func TestMigrations(t *testing.T) {
+	RegisterMigration(1, func(root *yaml.Node) error {
+		return RenameKey(root, "api", "http")
+	})
+	RegisterMigration(2, func(root *yaml.Node) error {
+		http := root.Content[keyIndex(root, "http")+1]
+		return RenameKey(http, "wait", "timeout")
+	})
+	defer func() {
+		migrations = map[int]Migration{}
+	}()
+
+	c := deprecatedCfg{}
+	err := LoadWith(&c, LoadParams{
+		File:        []byte("version: 1\napi:\n  wait: 3s\n"),
+		Environment: map[string]string{},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "3s", c.Http.Timeout)
+
+	err = LoadWith(&c, LoadParams{File: []byte("version: x\n"), Environment: map[string]string{}})
+	require.ErrorContains(t, err, "line 1: version must be an integer")
+}
+
+func testDeprecatedWarnOnce(t *testing.T) {
+	var got []Deprecation
+	onDeprecated := warnOnce(func(d Deprecation) { got = append(got, d) })
+
+	for i := 0; i < 3; i++ {
+		c := deprecatedCfg{}
+		err := LoadWith(&c, LoadParams{
+			File:         []byte("http:\n  idleTimeout: 5s\n"),
+			Prefix:       "APP_",
+			Environment:  map[string]string{"APP_HTTP_IDLE_TIMEOUT": "7s"},
+			OnDeprecated: onDeprecated,
+		})
+		require.NoError(t, err)
+	}
+	require.Equal(t, []Deprecation{
+		{Old: "idleTimeout", New: "timeout", Line: 2},
+		{Old: "APP_HTTP_IDLE_TIMEOUT", New: "APP_HTTP_TIMEOUT", Env: true},
+	}, got)
+	require.Nil(t, warnOnce(nil))
+}
@@ -0,0 +1,316 @@
+package cfg
+
+import (
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const noValue = "<none>"
+
+// Change is a single field-level difference between two configs.
+// Values are rendered masked, failed validation tags are empty when the field is valid.
+type Change struct {
+	Path     string `json:"path"`
+	Old      string `json:"old"`
+	New      string `json:"new"`
+	OldError string `json:"oldError,omitempty"`
+	NewError string `json:"newError,omitempty"`
+}
+
+// ValidationChanged reports whether the field validation outcome differs between the configs.
+func (c Change) ValidationChanged() bool {
+	return c.OldError != c.NewError
+}
+
+func (c Change) String() string {
+	switch {
+	case c.Old == c.New:
+		return fmt.Sprintf("! %s: %s -> %s", c.Path, outcome(c.OldError), outcome(c.NewError))
+	case c.ValidationChanged():
+		return fmt.Sprintf("! %s: %s -> %s (%s -> %s)",
+			c.Path, c.Old, c.New, outcome(c.OldError), outcome(c.NewError))
+	}
+
+	return fmt.Sprintf("~ %s: %s -> %s", c.Path, c.Old, c.New)
+}
+
+func outcome(tag string) string {
+	if tag == "" {
+		return "valid"
+	}
+
+	return "invalid: " + tag
+}
+
+// DiffReport is the result of DiffLoad.
+type DiffReport struct {
+	Changes []Change
+}
+
+func (r DiffReport) String() string {
+	lines := make([]string, 0, len(r.Changes))
+	for _, c := range r.Changes {
+		lines = append(lines, c.String())
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// Diff compares two configs of the same type field by field and returns the differences
+// keyed by YAML path. Values are masked the same way they are in JSON (see SafeString).
+func Diff(a, b interface{}) []Change {
+	return diff(flatten(a), flatten(b), nil, nil)
+}
+
+// DiffLoad loads both params into values produced by newDst through the LoadWith pipeline
+// and compares them. Validation failures do not stop the comparison, they are reported
+// on the changes where the validation outcome differs.
+func DiffLoad(newDst func() interface{}, left, right LoadParams) (*DiffReport, error) {
+	a, aErrs, err := loadForDiff(newDst(), left)
+	if err != nil {
+		return nil, fmt.Errorf("load left config error: %w", err)
+	}
+	b, bErrs, err := loadForDiff(newDst(), right)
+	if err != nil {
+		return nil, fmt.Errorf("load right config error: %w", err)
+	}
+
+	return &DiffReport{Changes: diff(flatten(a), flatten(b), aErrs, bErrs)}, nil
+}
+
+func loadForDiff(dst interface{}, params LoadParams) (interface{}, map[string]string, error) {
+	err := LoadWith(dst, params)
+	if err == nil {
+		return dst, nil, nil
+	}
+
+	var cfgErr Error
+	if !errors.As(err, &cfgErr) {
+		return nil, nil, err
+	}
+
+	failed := make(map[string]string)
+	for _, fe := range cfgErr.Fields() {
+		failed[fe.Field] = fe.Tag
+	}
+
+	return dst, failed, nil
+}
+
+type leaf struct {
+	path  string
+	ns    string
+	value string
+	raw   interface{}
+}
+
+func diff(a, b []leaf, aErrs, bErrs map[string]string) []Change {
+	right := make(map[string]leaf, len(b))
+	for _, l := range b {
+		right[l.path] = l
+	}
+
+	var changes []Change
+	seen := make(map[string]bool, len(a))
+	add := func(path, ns, before, after string) {
+		c := Change{Path: path, Old: before, New: after, OldError: aErrs[ns], NewError: bErrs[ns]}
+		if c.Old != c.New || c.ValidationChanged() {
+			changes = append(changes, c)
+		}
+	}
+
+	for _, l := range a {
+		seen[l.path] = true
+		r, ok := right[l.path]
+		switch {
+		case !ok:
+			add(l.path, l.ns, l.value, noValue)
+		case l.value == r.value && !reflect.DeepEqual(l.raw, r.raw):
+			// masked values may match while the secrets behind them differ
+			add(l.path, l.ns, l.value, r.value+" (changed)")
+		default:
+			add(l.path, l.ns, l.value, r.value)
+		}
+	}
+	for _, r := range b {
+		if !seen[r.path] {
+			add(r.path, r.ns, noValue, r.value)
+		}
+	}
+
+	return changes
+}
+
+func flatten(v interface{}) []leaf {
+	var res []leaf
+	flattenValue(reflect.ValueOf(v), "", "", &res)
+
+	return res
+}
+
+func flattenValue(v reflect.Value, path, ns string, res *[]leaf) {
+	if !v.IsValid() {
+		*res = append(*res, leaf{path: path, ns: ns, value: "null"})
+		return
+	}
+	if k := v.Kind(); k == reflect.Ptr || k == reflect.Interface {
+		if v.IsNil() {
+			*res = append(*res, leaf{path: path, ns: ns, value: "null"})
+			return
+		}
+		flattenValue(v.Elem(), path, ns, res)
+		return
+	}
+	if isLeaf(v.Type()) {
+		*res = append(*res, newLeaf(path, ns, v))
+		return
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			name, inline := yamlName(f)
+			if name == "-" {
+				continue
+			}
+			if inline {
+				flattenValue(v.Field(i), path, ns, res)
+				continue
+			}
+			flattenValue(v.Field(i), join(path, name), join(ns, f.Name), res)
+		}
+	case reflect.Map:
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
+		})
+		for _, k := range keys {
+			key := fmt.Sprint(k.Interface())
+			flattenValue(v.MapIndex(k), join(path, key), ns+"["+key+"]", res)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			idx := "[" + strconv.Itoa(i) + "]"
+			flattenValue(v.Index(i), path+idx, ns+idx, res)
+		}
+	default:
+		*res = append(*res, newLeaf(path, ns, v))
+	}
+}
+
+func newLeaf(path, ns string, v reflect.Value) leaf {
+	l := leaf{path: path, ns: ns, value: masked(v)}
+	if v.CanInterface() {
+		l.raw = v.Interface()
+	}
+
+	return l
+}
+
+func join(path, name string) string {
+	if path == "" {
+		return name
+	}
+
+	return path + "." + name
+}
+
+// yamlName returns the key yaml.v3 uses for the field and whether the field is inlined.
+func yamlName(f reflect.StructField) (string, bool) {
+	tag := f.Tag.Get("yaml")
+	name, opts, _ := strings.Cut(tag, ",")
+	if strings.Contains(opts, "inline") {
+		return "", true
+	}
+	if name == "" {
+		name = strings.ToLower(f.Name)
+	}
+
+	return name, false
+}
+
+var (
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	stringerType      = reflect.TypeOf((*fmt.Stringer)(nil)).Elem()
+)
+
+func isLeaf(t reflect.Type) bool {
+	for _, i := range []reflect.Type{jsonMarshalerType, textMarshalerType, stringerType} {
+		if t.Implements(i) || reflect.PointerTo(t).Implements(i) {
+			return true
+		}
+	}
+
+	switch t.Kind() {
+	case reflect.Struct, reflect.Map, reflect.Array:
+		return false
+	case reflect.Slice:
+		return t.Elem().Kind() == reflect.Uint8
+	}
+
+	return true
+}
+
+// masked renders a leaf value the way it would appear in JSON, so secrets stay masked,
+// falling back to fmt for everything else. URL passwords are masked too,
+// including the ones of plain strings holding a URL.
+func masked(v reflect.Value) string {
+	if !v.CanInterface() {
+		return "?"
+	}
+
+	i := v.Interface()
+	if _, ok := i.(json.Marshaler); !ok && v.CanAddr() {
+		i = v.Addr().Interface()
+	}
+	switch u := i.(type) {
+	case *url.URL:
+		return u.Redacted()
+	case url.URL:
+		return u.Redacted()
+	}
+
+	if m, ok := i.(json.Marshaler); ok {
+		b, err := m.MarshalJSON()
+		if err != nil {
+			return "<" + err.Error() + ">"
+		}
+		var s string
+		if json.Unmarshal(b, &s) == nil {
+			return s
+		}
+
+		return string(b)
+	}
+
+	return redactURL(fmt.Sprint(v.Interface()))
+}
+
+// redactURL masks the password of the string if it is a URL with one.
+func redactURL(s string) string {
+	if !strings.Contains(s, "://") || !strings.Contains(s, "@") {
+		return s
+	}
+	u, err := url.Parse(s)
+	if err != nil {
+		return s
+	}
+	if _, ok := u.User.Password(); !ok {
+		return s
+	}
+
+	return u.Redacted()
+}
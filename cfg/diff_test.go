@@ -0,0 +1,53 @@
+package cfg
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type diffCfg struct {
+	Http struct {
+		URL     string        `yaml:"url" validate:"required,url"`
+		Timeout time.Duration `yaml:"timeout"`
+	} `yaml:"http"`
+	Password SafeString        `yaml:"password"`
+	Labels   map[string]string `yaml:"labels"`
+	Hosts    []string          `yaml:"hosts"`
+}
+
+func TestDiff(t *testing.T) {
+	a, b := diffCfg{}, diffCfg{}
+	a.Http.URL, b.Http.URL = "http://a", "http://b"
+	a.Http.Timeout, b.Http.Timeout = time.Second, time.Second
+	a.Password, b.Password = "old-secret-password", "new-secret-password"
+	a.Labels = map[string]string{"team": "core"}
+	b.Hosts = []string{"h1"}
+
+	require.Equal(t, []Change{
+		{Path: "http.url", Old: "http://a", New: "http://b"},
+		{Path: "password", Old: "***************sword", New: "***************sword (changed)"},
+		{Path: "labels.team", Old: "core", New: noValue},
+		{Path: "hosts[0]", Old: noValue, New: "h1"},
+	}, Diff(&a, &b))
+}
+
+func TestDiffLoad(t *testing.T) {
+	report, err := DiffLoad(func() interface{} {
+		return &diffCfg{}
+	}, LoadParams{
+		File:        []byte("http:\n  url: http://a\n  timeout: 1s\n"),
+		Environment: map[string]string{},
+	}, LoadParams{
+		File:        []byte("http:\n  url: not-a-url\n  timeout: 2s\n"),
+		Environment: map[string]string{},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []Change{
+		{Path: "http.url", Old: "http://a", New: "not-a-url", NewError: "url"},
+		{Path: "http.timeout", Old: "1s", New: "2s"},
+	}, report.Changes)
+	require.Equal(t, "! http.url: http://a -> not-a-url (valid -> invalid: url)\n~ http.timeout: 1s -> 2s",
+		report.String())
+}
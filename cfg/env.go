@@ -20,11 +20,35 @@ import (
 //	   // handle error
 //	}
 func loadEnv(file string) error {
+	vars, err := readEnv(file)
+	if err != nil {
+		return err
+	}
+
+	for key, value := range vars {
+		currentVal := os.Getenv(key)
+		if currentVal != "" {
+			continue
+		}
+
+		err = os.Setenv(key, value)
+		if err != nil {
+			return fmt.Errorf("setting env %s=%s error: %w", key, value, err)
+		}
+	}
+
+	return nil
+}
+
+// readEnv parses the key=value file without touching the process environment.
+// If a key is repeated, the first value wins, the same way loadEnv never overrides a set variable.
+func readEnv(file string) (map[string]string, error) {
 	fileContents, err := os.ReadFile(file)
 	if err != nil {
-		return fmt.Errorf("reading file %s error: %w", file, err)
+		return nil, fmt.Errorf("reading file %s error: %w", file, err)
 	}
 
+	vars := make(map[string]string)
 	lines := strings.Split(string(fileContents), "\n")
 	for i, line := range lines {
 		trimmedLine := strings.TrimSpace(line)
@@ -35,27 +59,56 @@ func loadEnv(file string) error {
 
 		indexOfEqual := strings.Index(trimmedLine, "=")
 		if indexOfEqual == -1 {
-			return fmt.Errorf("file %s contains not expected line %s", file, trimmedLine)
+			return nil, fmt.Errorf("file %s contains not expected line %s", file, trimmedLine)
 		}
 
 		keyValuePair := strings.SplitN(trimmedLine, "=", 2)
 		if len(keyValuePair) != 2 {
-			return fmt.Errorf("line at %v is %s but expected to be key=value", i, trimmedLine)
+			return nil, fmt.Errorf("line at %v is %s but expected to be key=value", i, trimmedLine)
 		}
 
 		key, value := strings.TrimSpace(keyValuePair[0]), strings.TrimSpace(keyValuePair[1])
-		currentVal := os.Getenv(key)
-		if currentVal != "" {
+		if _, ok := vars[key]; ok {
 			continue
 		}
+		vars[key] = value
+	}
 
-		err = os.Setenv(key, value)
+	return vars, nil
+}
+
+// environ returns the process environment with the variables of the env file added
+// where the process does not set them, mirroring what loadEnv does in place.
+func environ(file string) (map[string]string, error) {
+	vars := make(map[string]string)
+	if file != "" {
+		fileVars, err := readEnv(file)
 		if err != nil {
-			return fmt.Errorf("setting env %s=%s error: %w", key, value, err)
+			return nil, err
 		}
+		vars = fileVars
 	}
 
-	return nil
+	for _, kv := range os.Environ() {
+		key, value, _ := strings.Cut(kv, "=")
+		if _, ok := vars[key]; !ok || value != "" {
+			vars[key] = value
+		}
+	}
+
+	return vars, nil
+}
+
+// processEnviron returns the process environment as is, the way env.Parse reads it
+// when no environment is provided.
+func processEnviron() map[string]string {
+	vars := make(map[string]string)
+	for _, kv := range os.Environ() {
+		key, value, _ := strings.Cut(kv, "=")
+		vars[key] = value
+	}
+
+	return vars
 }
 
 // isSkippable check whether a line is skippable
@@ -4,6 +4,7 @@ import (
 	"os"
 	"testing"
 
+	"github.com/caarlos0/env/v10"
 	"github.com/stretchr/testify/require"
 )
 
@@ -14,6 +15,28 @@ func TestDotEnv(t *testing.T) {
 	t.Run("testDotEnvDoesNotOverrideEnv", func(t *testing.T) {
 		testDotEnvDoesNotOverrideEnv1(t)
 	})
+	t.Run("testEmptyEnvKeepsYAML", testEmptyEnvKeepsYAML)
+}
+
+// testEmptyEnvKeepsYAML pins the process environment semantics of env.Parse:
+// a variable set to an empty string does not override the YAML value.
+func testEmptyEnvKeepsYAML(t *testing.T) {
+	t.Setenv("EMPTY_TEST_NAME", "")
+	t.Setenv("EMPTY_TEST_PORT", "8080")
+
+	var c struct {
+		Name string `yaml:"name" env:"NAME"`
+		Port string `yaml:"port" env:"PORT"`
+	}
+	err := LoadWith(&c, LoadParams{File: []byte("name: yaml\nport: \"80\"\n"), Prefix: "EMPTY_TEST_"})
+	require.NoError(t, err)
+	require.Equal(t, "yaml", c.Name)
+	require.Equal(t, "8080", c.Port)
+
+	expected := c
+	expected.Name, expected.Port = "yaml", "80"
+	require.NoError(t, env.ParseWithOptions(&expected, env.Options{Prefix: "EMPTY_TEST_"}))
+	require.Equal(t, expected, c)
 }
 
 func testLoad(t *testing.T) {
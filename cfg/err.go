@@ -2,7 +2,10 @@ package cfg
 
 import (
 	"bytes"
+	"errors"
 	"strings"
+
+	"github.com/go-playground/validator/v10"
 )
 
 type Error struct {
@@ -20,3 +23,39 @@ func (e Error) Error() string {
 
 	return strings.TrimSpace(buff.String())
 }
+
+// Unwrap returns the accumulated validation errors, so errors.As can reach
+// each validator.FieldError.
+func (e Error) Unwrap() []error {
+	return e.errors
+}
+
+// FieldError is a structured validation failure of a config field.
+// Field is the struct namespace without the root type name, e.g. "Http.URL".
+type FieldError struct {
+	Field   string `json:"field"`
+	Tag     string `json:"tag"`
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message"`
+}
+
+// Fields returns the validation failures in a structured form.
+func (e Error) Fields() []FieldError {
+	res := make([]FieldError, 0, len(e.errors))
+	for _, err := range e.errors {
+		var fe validator.FieldError
+		if !errors.As(err, &fe) {
+			res = append(res, FieldError{Message: err.Error()})
+			continue
+		}
+		_, field, _ := strings.Cut(fe.StructNamespace(), ".")
+		res = append(res, FieldError{
+			Field:   field,
+			Tag:     fe.Tag(),
+			Param:   fe.Param(),
+			Message: fe.Error(),
+		})
+	}
+
+	return res
+}
@@ -0,0 +1,239 @@
+// Package flags implements typed feature flags defined in the config YAML and overridable via env.
+//
+// Flags are defined under the `flags` key:
+//
+//	flags:
+//	  newCheckout:
+//	    type: percentage
+//	    percentage: 25
+//	  betaSearch:
+//	    type: allowlist
+//	    users: [42, 1337]
+//	  darkMode:
+//	    type: bool
+//	    enabled: true
+//
+// and overridden with the FLAGS env variable (with the cfg prefix): FLAGS=newCheckout:50,darkMode:false.
+// A true/false override forces a flag of any type on or off, a number sets the percentage of a percentage flag.
+package flags
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+
+	"github.com/sorohimm/utils/cfg"
+	"github.com/sorohimm/utils/log"
+)
+
+const (
+	TypeBool       = "bool"
+	TypePercentage = "percentage"
+	TypeAllowList  = "allowlist"
+)
+
+// Reasons of evaluation results.
+const (
+	ReasonUnknown   = "unknown flag"
+	ReasonForced    = "forced"
+	ReasonBool      = "bool"
+	ReasonNoUser    = "no user id"
+	ReasonRollout   = "rollout"
+	ReasonAllowList = "allowlist"
+)
+
+const (
+	buckets          = 10000
+	percentToBuckets = buckets / 100
+)
+
+// Definition is a flag as defined in the config YAML.
+type Definition struct {
+	Type       string   `yaml:"type" validate:"required,oneof=bool percentage allowlist"`
+	Enabled    bool     `yaml:"enabled"`
+	Percentage float64  `yaml:"percentage" validate:"min=0,max=100"`
+	Users      []string `yaml:"users"`
+
+	forced *bool
+}
+
+// Config is the part of a service config holding the flags, embed it or load it with cfg.Load.
+type Config struct {
+	Flags     map[string]Definition `yaml:"flags" validate:"dive"`
+	Overrides map[string]string     `env:"FLAGS"`
+}
+
+// Evaluation is the result of a single flag evaluation.
+type Evaluation struct {
+	Flag    string
+	UserId  string
+	Enabled bool
+	Reason  string
+}
+
+type flagSet struct {
+	defs  map[string]Definition
+	users map[string]map[string]struct{}
+}
+
+// Set evaluates flags. It is safe for concurrent use and can be updated at runtime.
+type Set struct {
+	current atomic.Pointer[flagSet]
+
+	mu        sync.RWMutex
+	recorders []func(context.Context, Evaluation)
+}
+
+// Load loads the flags of the config set up by cfg.Setup.
+func Load() (*Set, error) {
+	c := Config{}
+	if err := cfg.Load(&c); err != nil {
+		return nil, err
+	}
+
+	return New(&c)
+}
+
+// New creates a Set from the config, applying env overrides.
+func New(c *Config) (*Set, error) {
+	s := &Set{}
+	if err := s.Update(c); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Update atomically replaces the flags with the ones from the config.
+func (s *Set) Update(c *Config) error {
+	fs := &flagSet{
+		defs:  make(map[string]Definition, len(c.Flags)),
+		users: make(map[string]map[string]struct{}),
+	}
+	for name, def := range c.Flags {
+		if def.Type == TypeAllowList {
+			users := make(map[string]struct{}, len(def.Users))
+			for _, u := range def.Users {
+				users[u] = struct{}{}
+			}
+			fs.users[name] = users
+		}
+		fs.defs[name] = def
+	}
+
+	for name, value := range c.Overrides {
+		def, ok := fs.defs[name]
+		if !ok {
+			return fmt.Errorf("override of unknown flag %s", name)
+		}
+		if err := override(&def, value); err != nil {
+			return fmt.Errorf("override of flag %s error: %w", name, err)
+		}
+		fs.defs[name] = def
+	}
+
+	s.current.Store(fs)
+
+	return nil
+}
+
+func override(def *Definition, value string) error {
+	if forced, err := strconv.ParseBool(value); err == nil {
+		def.forced = &forced
+		return nil
+	}
+
+	if def.Type != TypePercentage {
+		return fmt.Errorf("%q is not a bool", value)
+	}
+	p, err := strconv.ParseFloat(value, 64)
+	if err != nil || p < 0 || p > 100 {
+		return fmt.Errorf("%q is neither a bool nor a percentage", value)
+	}
+	def.Percentage = p
+
+	return nil
+}
+
+// Bind updates the Set on every config reload. The get function extracts the flags config
+// from the service config value held by the reloader. Invalid flags are logged with the logger
+// and the previous flags are kept.
+func (s *Set) Bind(logger *log.Zap, r *cfg.Reloader, get func(interface{}) *Config) {
+	r.OnChange(func(c interface{}) {
+		if err := s.Update(get(c)); err != nil {
+			logger.Error("update feature flags error", zap.Error(err))
+		}
+	})
+}
+
+// OnEvaluate registers a recorder called with every evaluation.
+func (s *Set) OnEvaluate(fn func(context.Context, Evaluation)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.recorders = append(s.recorders, fn)
+}
+
+// Enabled evaluates the flag for the user carried by ctx (see log.WithUserId).
+// Every evaluation is logged at debug level with the context logger (see log.FromContext).
+func (s *Set) Enabled(ctx context.Context, name string) bool {
+	e := s.Evaluate(ctx, name)
+
+	log.FromContext(ctx).Debug("feature flag evaluated",
+		zap.String("flag", e.Flag),
+		zap.String(log.UserId, e.UserId),
+		zap.Bool("enabled", e.Enabled),
+		zap.String("reason", e.Reason),
+	)
+
+	s.mu.RLock()
+	recorders := s.recorders
+	s.mu.RUnlock()
+	for _, fn := range recorders {
+		fn(ctx, e)
+	}
+
+	return e.Enabled
+}
+
+// Evaluate evaluates the flag without recording the result.
+func (s *Set) Evaluate(ctx context.Context, name string) Evaluation {
+	userId := log.UserIdFromContext(ctx)
+	e := Evaluation{Flag: name, UserId: userId}
+
+	fs := s.current.Load()
+	def, ok := fs.defs[name]
+
+	switch {
+	case !ok:
+		e.Reason = ReasonUnknown
+	case def.forced != nil:
+		e.Enabled, e.Reason = *def.forced, ReasonForced
+	case def.Type == TypeBool:
+		e.Enabled, e.Reason = def.Enabled, ReasonBool
+	case userId == "":
+		e.Reason = ReasonNoUser
+	case def.Type == TypePercentage:
+		e.Enabled, e.Reason = Bucket(name, userId) < int(def.Percentage*percentToBuckets), ReasonRollout
+	case def.Type == TypeAllowList:
+		_, e.Enabled = fs.users[name][userId]
+		e.Reason = ReasonAllowList
+	}
+
+	return e
+}
+
+// Bucket deterministically maps the user to one of 10000 buckets of the flag,
+// so a user keeps the result while the rollout percentage grows.
+func Bucket(flag, userId string) int {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(flag))
+	_, _ = h.Write([]byte{':'})
+	_, _ = h.Write([]byte(userId))
+
+	return int(h.Sum32() % buckets)
+}
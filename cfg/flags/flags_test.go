@@ -0,0 +1,115 @@
+package flags
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/sorohimm/utils/cfg"
+	"github.com/sorohimm/utils/cfg/cfgtest"
+	"github.com/sorohimm/utils/log"
+	"github.com/sorohimm/utils/log/logtest"
+)
+
+const flagsYaml = `
+	flags:
+	  darkMode:
+	    type: bool
+	    enabled: true
+	  newCheckout:
+	    type: percentage
+	    percentage: 25
+	  betaSearch:
+	    type: allowlist
+	    users: ["42"]
+`
+
+func TestEnabled(t *testing.T) {
+	c := Config{}
+	cfgtest.Load(t, &c, flagsYaml, nil)
+	s, err := New(&c)
+	require.NoError(t, err)
+
+	var evaluations []Evaluation
+	s.OnEvaluate(func(_ context.Context, e Evaluation) {
+		evaluations = append(evaluations, e)
+	})
+
+	ctx := context.Background()
+	user := log.WithUserId(ctx, "42")
+	require.True(t, s.Enabled(ctx, "darkMode"))
+	require.False(t, s.Enabled(ctx, "betaSearch"))
+	require.True(t, s.Enabled(user, "betaSearch"))
+	require.False(t, s.Enabled(log.WithUserId(ctx, "7"), "betaSearch"))
+	require.False(t, s.Enabled(user, "unknown"))
+	require.Equal(t, []Evaluation{
+		{Flag: "darkMode", Enabled: true, Reason: ReasonBool},
+		{Flag: "betaSearch", Reason: ReasonNoUser},
+		{Flag: "betaSearch", UserId: "42", Enabled: true, Reason: ReasonAllowList},
+		{Flag: "betaSearch", UserId: "7", Reason: ReasonAllowList},
+		{Flag: "unknown", UserId: "42", Reason: ReasonUnknown},
+	}, evaluations)
+}
+
+func TestRollout(t *testing.T) {
+	c := Config{}
+	cfgtest.Load(t, &c, flagsYaml, nil)
+	s, err := New(&c)
+	require.NoError(t, err)
+
+	enabled := 0
+	for i := 0; i < 10000; i++ {
+		ctx := log.WithUserId(context.Background(), strconv.Itoa(i))
+		if s.Evaluate(ctx, "newCheckout").Enabled {
+			enabled++
+		}
+		require.Equal(t, Bucket("newCheckout", strconv.Itoa(i)), Bucket("newCheckout", strconv.Itoa(i)))
+	}
+	require.InDelta(t, 2500, enabled, 200)
+}
+
+func TestOverrides(t *testing.T) {
+	c := Config{}
+	cfgtest.Load(t, &c, flagsYaml, map[string]string{"FLAGS": "darkMode:false,newCheckout:100,betaSearch:true"})
+	s, err := New(&c)
+	require.NoError(t, err)
+
+	user := log.WithUserId(context.Background(), "7")
+	require.False(t, s.Enabled(user, "darkMode"))
+	require.True(t, s.Enabled(user, "newCheckout"))
+	require.True(t, s.Enabled(user, "betaSearch"))
+
+	cfgtest.Load(t, &c, flagsYaml, map[string]string{"FLAGS": "darkMode:50"})
+	_, err = New(&c)
+	require.ErrorContains(t, err, `override of flag darkMode error: "50" is not a bool`)
+}
+
+type serviceCfg struct {
+	Config `yaml:",inline"`
+}
+
+func TestBind(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cfg.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(cfgtest.Dedent(flagsYaml)), 0o600))
+
+	r, err := cfg.NewReloaderWith(func() interface{} {
+		return &serviceCfg{}
+	}, cfg.LoadParams{Path: path, Environment: map[string]string{}}, nil)
+	require.NoError(t, err)
+	require.NoError(t, r.Reload())
+
+	s, err := New(&r.Current().(*serviceCfg).Config)
+	require.NoError(t, err)
+	s.Bind(logtest.New(t, logtest.Params{}).Zap, r, func(c interface{}) *Config {
+		return &c.(*serviceCfg).Config
+	})
+	require.True(t, s.Enabled(context.Background(), "darkMode"))
+
+	require.NoError(t, os.WriteFile(path, []byte("flags:\n  darkMode:\n    type: bool\n"), 0o600))
+	require.NoError(t, r.Reload())
+	require.False(t, s.Enabled(context.Background(), "darkMode"))
+}
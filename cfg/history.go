@@ -0,0 +1,237 @@
+package cfg
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const HistorySizeDefault = 32
+
+// Sources of applied config snapshots.
+const (
+	SourceInitial  = "initial"
+	SourceFile     = "file"
+	SourceRemote   = "remote"
+	SourceRollback = "rollback"
+)
+
+// Snapshot is an applied config value with the masked diff from the previous one.
+type Snapshot struct {
+	Version int       `json:"version"`
+	Hash    string    `json:"hash"`
+	Time    time.Time `json:"time"`
+	Source  string    `json:"source"`
+	Changes []Change  `json:"changes"`
+
+	value interface{}
+}
+
+// Value returns the config value of the snapshot. It is the unmasked value Rollback applies,
+// it is never serialized: the JSON form only has the hash and the masked changes.
+func (s Snapshot) Value() interface{} {
+	return s.value
+}
+
+// NewHistory creates a History keeping the last size snapshots.
+func NewHistory(size int) *History {
+	if size <= 0 {
+		size = HistorySizeDefault
+	}
+
+	return &History{size: size}
+}
+
+// History is an in-memory ring of applied config snapshots.
+type History struct {
+	mu        sync.RWMutex
+	size      int
+	snapshots []Snapshot
+	version   int
+}
+
+// Record adds the config value to the history.
+func (h *History) Record(value interface{}, source string) Snapshot {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.version++
+	s := Snapshot{
+		Version: h.version,
+		Hash:    hash(value),
+		Time:    time.Now(),
+		Source:  source,
+		value:   value,
+	}
+	if len(h.snapshots) > 0 {
+		s.Changes = Diff(h.snapshots[len(h.snapshots)-1].value, value)
+	}
+
+	h.snapshots = append(h.snapshots, s)
+	if len(h.snapshots) > h.size {
+		h.snapshots = h.snapshots[len(h.snapshots)-h.size:]
+	}
+
+	return s
+}
+
+// Snapshots returns the kept snapshots, the oldest first.
+func (h *History) Snapshots() []Snapshot {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return append([]Snapshot(nil), h.snapshots...)
+}
+
+// Get returns the snapshot of the version if it is still kept.
+func (h *History) Get(version int) (Snapshot, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for _, s := range h.snapshots {
+		if s.Version == version {
+			return s, true
+		}
+	}
+
+	return Snapshot{}, false
+}
+
+// hashKey keys the snapshot hashes, so the hashes exposed by NewHistoryHandler cannot be used
+// to brute-force the secrets they cover. They are only comparable within the process.
+var hashKey = func() []byte {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		panic(fmt.Sprintf("generate history hash key error: %v", err))
+	}
+
+	return key
+}()
+
+// hash returns an HMAC-SHA256 of the config value keyed by hashKey. Every leaf is written in
+// a canonical encoding of its underlying data, including unexported fields, so a changed secret
+// changes the hash even when it is hidden behind a masking String method (e.g. the password
+// of types.DSN) while the diff shows it masked.
+func hash(value interface{}) string {
+	h := hmac.New(sha256.New, hashKey)
+	for _, l := range flatten(value) {
+		_, _ = fmt.Fprintf(h, "%s=", l.path)
+		canonical(h, reflect.ValueOf(l.raw), map[uintptr]bool{})
+		_, _ = h.Write([]byte{'\n'})
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// canonical writes the value by its kind rather than by its formatting methods,
+// map entries are sorted by their encoding and pointer cycles are cut.
+func canonical(w io.Writer, v reflect.Value, seen map[uintptr]bool) {
+	if !v.IsValid() {
+		_, _ = io.WriteString(w, "nil")
+		return
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			_, _ = io.WriteString(w, "nil")
+			return
+		}
+		if seen[v.Pointer()] {
+			_, _ = io.WriteString(w, "cycle")
+			return
+		}
+		seen[v.Pointer()] = true
+		_, _ = io.WriteString(w, "&")
+		canonical(w, v.Elem(), seen)
+		delete(seen, v.Pointer())
+	case reflect.Interface:
+		canonical(w, v.Elem(), seen)
+	case reflect.Struct:
+		_, _ = io.WriteString(w, "{")
+		for i := 0; i < v.NumField(); i++ {
+			_, _ = fmt.Fprintf(w, "%s:", v.Type().Field(i).Name)
+			canonical(w, v.Field(i), seen)
+			_, _ = io.WriteString(w, ";")
+		}
+		_, _ = io.WriteString(w, "}")
+	case reflect.Map:
+		entries := make([]string, 0, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			var b strings.Builder
+			canonical(&b, iter.Key(), seen)
+			_, _ = b.WriteString(":")
+			canonical(&b, iter.Value(), seen)
+			entries = append(entries, b.String())
+		}
+		sort.Strings(entries)
+		_, _ = fmt.Fprintf(w, "map%d{%s}", len(entries), strings.Join(entries, ";"))
+	case reflect.Slice, reflect.Array:
+		_, _ = fmt.Fprintf(w, "[%d:", v.Len())
+		for i := 0; i < v.Len(); i++ {
+			canonical(w, v.Index(i), seen)
+			_, _ = io.WriteString(w, ";")
+		}
+		_, _ = io.WriteString(w, "]")
+	case reflect.String:
+		_, _ = io.WriteString(w, strconv.Quote(v.String()))
+	case reflect.Bool:
+		_, _ = io.WriteString(w, strconv.FormatBool(v.Bool()))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		_, _ = io.WriteString(w, strconv.FormatInt(v.Int(), 10))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		_, _ = io.WriteString(w, strconv.FormatUint(v.Uint(), 10))
+	case reflect.Float32, reflect.Float64:
+		_, _ = io.WriteString(w, strconv.FormatFloat(v.Float(), 'g', -1, 64))
+	case reflect.Complex64, reflect.Complex128:
+		_, _ = io.WriteString(w, strconv.FormatComplex(v.Complex(), 'g', -1, 128))
+	default:
+		// functions, channels and unsafe pointers carry no config data
+		_, _ = fmt.Fprintf(w, "<%s>", v.Kind())
+	}
+}
+
+// NewHistoryHandler returns an http.Handler exposing the history of the reloader:
+// GET lists the snapshots as JSON, POST with the version query parameter
+// rolls the config back to that snapshot.
+func NewHistoryHandler(r *Reloader) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.Method {
+		case http.MethodGet:
+			writeJSON(w, http.StatusOK, r.History().Snapshots())
+		case http.MethodPost:
+			version, err := strconv.Atoi(req.URL.Query().Get("version"))
+			if err != nil {
+				http.Error(w, "version must be an integer", http.StatusBadRequest)
+				return
+			}
+			s, err := r.Rollback(version)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusNotFound)
+				return
+			}
+			writeJSON(w, http.StatusOK, s)
+		default:
+			w.Header().Set("Allow", "GET, POST")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		}
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,162 @@
+package cfg
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// includeTag replaces the tagged node with the root of another document: `db: !include db.yaml`
+	includeTag = "!include"
+	// includesKey is the top-level list of documents the file is merged on top of
+	includesKey = "includes"
+)
+
+// resolveIncludes replaces `!include` nodes and the top-level `includes:` list of the document
+// read from path with the contents of the referenced files. Paths are relative to the including file.
+// Files listed in `includes:` are merged in order and the document itself is merged on top of them.
+func resolveIncludes(root *yaml.Node, path string) error {
+	in := &includer{}
+	if path != "" {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		in.stack = []string{abs}
+	}
+
+	return in.resolveRoot(root, path)
+}
+
+type includer struct {
+	stack []string // absolute paths of the files being resolved, used for cycle detection
+}
+
+func (in *includer) resolveRoot(root *yaml.Node, file string) error {
+	base := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+
+	if idx := keyIndex(root, includesKey); idx >= 0 {
+		list := root.Content[idx+1]
+		if list.Kind != yaml.SequenceNode {
+			return fmt.Errorf("%s:%d: %s must be a list of files", name(file), list.Line, includesKey)
+		}
+		for _, item := range list.Content {
+			included, err := in.load(file, item)
+			if err != nil {
+				return err
+			}
+			merge(base, included)
+		}
+		root.Content = append(root.Content[:idx], root.Content[idx+2:]...)
+	}
+
+	if err := in.resolve(root, file); err != nil {
+		return err
+	}
+	if len(base.Content) == 0 {
+		return nil
+	}
+
+	merge(base, root)
+	*root = *base
+
+	return nil
+}
+
+func (in *includer) resolve(node *yaml.Node, file string) error {
+	if node.Tag == includeTag {
+		included, err := in.load(file, node)
+		if err != nil {
+			return err
+		}
+		*node = *included
+
+		return nil
+	}
+
+	for _, child := range node.Content {
+		if err := in.resolve(child, file); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// load reads the file referenced by the node of the including file and resolves its own includes.
+func (in *includer) load(file string, ref *yaml.Node) (*yaml.Node, error) {
+	if ref.Kind != yaml.ScalarNode || ref.Value == "" {
+		return nil, fmt.Errorf("%s:%d: include must be a file path", name(file), ref.Line)
+	}
+
+	path := ref.Value
+	if !filepath.IsAbs(path) && file != "" {
+		path = filepath.Join(filepath.Dir(file), path)
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%d: include %s: %w", name(file), ref.Line, ref.Value, err)
+	}
+
+	for _, p := range in.stack {
+		if p == abs {
+			return nil, fmt.Errorf("%s:%d: include cycle: %s -> %s",
+				name(file), ref.Line, strings.Join(in.stack, " -> "), abs)
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%d: include %s: %w", name(file), ref.Line, ref.Value, err)
+	}
+
+	var doc yaml.Node
+	if err = yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if len(doc.Content) == 0 {
+		return &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}, nil
+	}
+
+	in.stack = append(in.stack, abs)
+	defer func() {
+		in.stack = in.stack[:len(in.stack)-1]
+	}()
+
+	root := doc.Content[0]
+	if err = in.resolveRoot(root, path); err != nil {
+		return nil, err
+	}
+
+	return root, nil
+}
+
+func name(file string) string {
+	if file == "" {
+		return "<config>"
+	}
+
+	return file
+}
+
+// merge deep-merges src into dst: mappings are merged key by key recursively,
+// any other node (scalar, sequence or a mapping over a non-mapping) from src replaces the one in dst.
+func merge(dst, src *yaml.Node) {
+	if dst.Kind != yaml.MappingNode || src.Kind != yaml.MappingNode {
+		*dst = *src
+		return
+	}
+
+	for i := 0; i+1 < len(src.Content); i += 2 {
+		key, value := src.Content[i], src.Content[i+1]
+		if idx := keyIndex(dst, key.Value); idx >= 0 {
+			merge(dst.Content[idx+1], value)
+			continue
+		}
+		dst.Content = append(dst.Content, key, value)
+	}
+}
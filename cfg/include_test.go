@@ -0,0 +1,44 @@
+package cfg
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type includeCfg struct {
+	Http struct {
+		URL  string `yaml:"url"`
+		Port int    `yaml:"port"`
+	} `yaml:"http"`
+	DB struct {
+		DSN  string `yaml:"dsn"`
+		Pool struct {
+			Size int `yaml:"size"`
+		} `yaml:"pool"`
+	} `yaml:"db"`
+}
+
+func loadFile(t *testing.T, dst interface{}, path string) error {
+	t.Helper()
+	file, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	return LoadWith(dst, LoadParams{File: file, Path: path, Environment: map[string]string{}})
+}
+
+func TestIncludes(t *testing.T) {
+	c := includeCfg{}
+	require.NoError(t, loadFile(t, &c, "testdata/include/main.yaml"))
+	require.Equal(t, "http://base", c.Http.URL)
+	require.Equal(t, 8080, c.Http.Port)
+	require.Equal(t, "postgres://localhost", c.DB.DSN)
+	require.Equal(t, 10, c.DB.Pool.Size)
+
+	err := loadFile(t, &c, "testdata/include/cycle.yaml")
+	require.ErrorContains(t, err, "testdata/include/cycle2.yaml:1: include cycle")
+
+	err = LoadWith(&c, LoadParams{File: []byte("db: !include missing.yaml\n"), Environment: map[string]string{}})
+	require.ErrorContains(t, err, "<config>:1: include missing.yaml")
+}
@@ -0,0 +1,90 @@
+package kv
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+const PollIntervalDefault = time.Second
+
+// NewFile creates a KV store backed by the directory: every regular file is a key
+// (its path relative to dir with "/" separators) and its contents is the value.
+func NewFile(dir string) *File {
+	return &File{dir: dir, PollInterval: PollIntervalDefault}
+}
+
+// File is a local file-backed KV store, a stand-in for running services without the real store.
+// Long polling is emulated by polling the directory.
+type File struct {
+	dir          string
+	PollInterval time.Duration
+}
+
+// Get returns the pairs under the prefix, polling until their contents change if waitIndex is set.
+// The index is a hash of the pairs, so any change of a key or value changes it.
+func (o *File) Get(ctx context.Context, prefix string, waitIndex uint64) (map[string]string, uint64, error) {
+	for {
+		pairs, index, err := o.read(prefix)
+		if err != nil || waitIndex == 0 || index != waitIndex {
+			return pairs, index, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, 0, ctx.Err()
+		case <-time.After(o.PollInterval):
+		}
+	}
+}
+
+func (o *File) read(prefix string) (map[string]string, uint64, error) {
+	res := make(map[string]string)
+	root := filepath.Join(o.dir, filepath.FromSlash(prefix))
+
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(o.dir, path)
+		if err != nil {
+			return err
+		}
+		res[filepath.ToSlash(rel)] = string(data)
+
+		return nil
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return nil, 0, fmt.Errorf("read %s error: %w", root, err)
+	}
+
+	return res, hash(res), nil
+}
+
+func hash(pairs map[string]string) uint64 {
+	keys := make([]string, 0, len(pairs))
+	for k := range pairs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	h := fnv.New64a()
+	for _, k := range keys {
+		_, _ = h.Write([]byte(k))
+		_, _ = h.Write([]byte{0})
+		_, _ = h.Write([]byte(pairs[k]))
+		_, _ = h.Write([]byte{0})
+	}
+
+	// zero means "do not wait" for Get
+	return h.Sum64() | 1
+}
@@ -0,0 +1,96 @@
+// Package kv contains cfg.KV implementations: a Consul-style HTTP client
+// and in-memory and file-backed stand-ins for local runs and tests.
+package kv
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	indexHeader     = "X-Consul-Index"
+	WaitTimeDefault = 5 * time.Minute
+)
+
+// NewHTTP creates a client of the Consul KV HTTP API (GET /v1/kv/<prefix>?recurse) at the address.
+// Long polling uses blocking queries with the index and wait parameters.
+func NewHTTP(address string, client *http.Client) *HTTP {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	return &HTTP{
+		address:  strings.TrimRight(address, "/"),
+		client:   client,
+		WaitTime: WaitTimeDefault,
+	}
+}
+
+// HTTP is a Consul-style KV store client.
+type HTTP struct {
+	address string
+	client  *http.Client
+	// Token is sent as the X-Consul-Token header when set
+	Token string
+	// WaitTime is the maximum duration of a blocking query
+	WaitTime time.Duration
+}
+
+type httpPair struct {
+	Key   string `json:"Key"`
+	Value []byte `json:"Value"`
+}
+
+// Get returns the pairs under the prefix, blocking until the index changes if waitIndex is set.
+func (o *HTTP) Get(ctx context.Context, prefix string, waitIndex uint64) (map[string]string, uint64, error) {
+	query := url.Values{"recurse": []string{"true"}}
+	if waitIndex != 0 {
+		query.Set("index", strconv.FormatUint(waitIndex, 10))
+		query.Set("wait", o.WaitTime.String())
+	}
+	u := o.address + "/v1/kv/" + strings.TrimLeft(prefix, "/") + "?" + query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, 0, fmt.Errorf("new request error: %w", err)
+	}
+	if o.Token != "" {
+		req.Header.Set("X-Consul-Token", o.Token)
+	}
+
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return nil, 0, fmt.Errorf("request error: %w", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	index, _ := strconv.ParseUint(resp.Header.Get(indexHeader), 10, 64)
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return map[string]string{}, index, nil
+	default:
+		return nil, 0, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	var pairs []httpPair
+	if err = json.NewDecoder(resp.Body).Decode(&pairs); err != nil {
+		return nil, 0, fmt.Errorf("decode response error: %w", err)
+	}
+
+	res := make(map[string]string, len(pairs))
+	for _, p := range pairs {
+		res[p.Key] = string(p.Value)
+	}
+
+	return res, index, nil
+}
@@ -0,0 +1,77 @@
+package kv
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTP(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/v1/kv/app", r.URL.Path)
+		require.Equal(t, "true", r.URL.Query().Get("recurse"))
+		w.Header().Set(indexHeader, "42")
+		if r.URL.Query().Get("index") == "42" {
+			w.Header().Set(indexHeader, "43")
+		}
+		_, _ = w.Write([]byte(`[{"Key":"app/","Value":null},{"Key":"app/http/port","Value":"ODA4MA=="}]`))
+	}))
+	defer srv.Close()
+
+	store := NewHTTP(srv.URL, srv.Client())
+	pairs, index, err := store.Get(context.Background(), "app", 0)
+	require.NoError(t, err)
+	require.Equal(t, uint64(42), index)
+	require.Equal(t, map[string]string{"app/": "", "app/http/port": "8080"}, pairs)
+
+	_, index, err = store.Get(context.Background(), "app", 42)
+	require.NoError(t, err)
+	require.Equal(t, uint64(43), index)
+}
+
+func TestFile(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "app", "http"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "app", "http", "port"), []byte("8080"), 0o600))
+
+	store := NewFile(dir)
+	store.PollInterval = 10 * time.Millisecond
+	pairs, index, err := store.Get(context.Background(), "app", 0)
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"app/http/port": "8080"}, pairs)
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		_ = os.WriteFile(filepath.Join(dir, "app", "http", "port"), []byte("9090"), 0o600)
+	}()
+	pairs, newIndex, err := store.Get(context.Background(), "app", index)
+	require.NoError(t, err)
+	require.NotEqual(t, index, newIndex)
+	require.Equal(t, map[string]string{"app/http/port": "9090"}, pairs)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, _, err = store.Get(ctx, "app", newIndex)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestMemory(t *testing.T) {
+	store := NewMemory()
+	store.Set("app/a", "1")
+	store.Set("app2/a", "2")
+	pairs, index, err := store.Get(context.Background(), "app", 0)
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"app/a": "1"}, pairs)
+
+	go store.Delete("app/a")
+	pairs, newIndex, err := store.Get(context.Background(), "app", index)
+	require.NoError(t, err)
+	require.Greater(t, newIndex, index)
+	require.Empty(t, pairs)
+}
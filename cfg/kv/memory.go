@@ -0,0 +1,81 @@
+package kv
+
+import (
+	"context"
+	"strings"
+	"sync"
+)
+
+// NewMemory creates an in-memory KV store.
+func NewMemory() *Memory {
+	return &Memory{
+		pairs:   map[string]string{},
+		index:   1,
+		changed: make(chan struct{}),
+	}
+}
+
+// Memory is an in-memory KV store supporting long polling, a stand-in for tests.
+type Memory struct {
+	mu      sync.Mutex
+	pairs   map[string]string
+	index   uint64
+	changed chan struct{}
+}
+
+// Set sets the key and wakes up the waiting Get calls.
+func (o *Memory) Set(key, value string) {
+	o.update(func() {
+		o.pairs[key] = value
+	})
+}
+
+// Delete deletes the key and wakes up the waiting Get calls.
+func (o *Memory) Delete(key string) {
+	o.update(func() {
+		delete(o.pairs, key)
+	})
+}
+
+func (o *Memory) update(fn func()) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	fn()
+	o.index++
+	close(o.changed)
+	o.changed = make(chan struct{})
+}
+
+// Get returns the pairs under the prefix, blocking until the next change if waitIndex is current.
+func (o *Memory) Get(ctx context.Context, prefix string, waitIndex uint64) (map[string]string, uint64, error) {
+	o.mu.Lock()
+	for waitIndex != 0 && waitIndex == o.index {
+		changed := o.changed
+		o.mu.Unlock()
+		select {
+		case <-ctx.Done():
+			return nil, 0, ctx.Err()
+		case <-changed:
+		}
+		o.mu.Lock()
+	}
+	defer o.mu.Unlock()
+
+	res := make(map[string]string)
+	for k, v := range o.pairs {
+		if HasPrefix(k, prefix) {
+			res[k] = v
+		}
+	}
+
+	return res, o.index, nil
+}
+
+// HasPrefix reports whether the key is the prefix or lies under it, matching whole "/" separated
+// segments, so the "app" prefix does not match "app2/key". Leading and trailing slashes are ignored.
+func HasPrefix(key, prefix string) bool {
+	key, prefix = strings.TrimLeft(key, "/"), strings.Trim(prefix, "/")
+
+	return prefix == "" || key == prefix || strings.HasPrefix(key, prefix+"/")
+}
@@ -0,0 +1,208 @@
+package cfg
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+const reloadRetryDefault = time.Second
+
+// Reloader keeps a config value up to date with its sources. Every reload runs
+// the full Load pipeline into a new value, so readers never see a partially updated config,
+// and a config that fails to load or validate is never applied.
+// Applied values are recorded in the History and can be rolled back to.
+type Reloader struct {
+	newDst func() interface{}
+	params LoadParams
+	remote *Remote
+	index  uint64
+
+	history *History
+
+	mu        sync.RWMutex
+	current   interface{}
+	onChange  []func(interface{})
+	onError   []func(error)
+	reloading sync.Mutex
+}
+
+// NewReloader loads the config set up by Setup into a value produced by newDst
+// and returns a Reloader holding it.
+//
+// Example usage:
+//
+//	r, err := NewReloader(func() interface{} { return &Cfg{} })
+//	if err != nil {
+//	    // handle error
+//	}
+//	r.OnChange(func(c interface{}) { apply(c.(*Cfg)) })
+//	g.Add(func() error { return r.Run(ctx) }, func(error) { cancel() })
+func NewReloader(newDst func() interface{}) (*Reloader, error) {
+	if cfg == nil {
+		return nil, fmt.Errorf("config is nil, check setup")
+	}
+
+	return NewReloaderWith(newDst, LoadParams{
+		File:         cfg.file,
+		Path:         cfg.path,
+		Remote:       cfg.remote,
+		RemoteIndex:  cfg.remoteIndex,
+		Prefix:       cfg.prefix,
+		OnDeprecated: cfg.onDeprecated,
+	}, cfg.remoteSource)
+}
+
+// NewReloaderWith is like NewReloader but loads the provided params instead of the global config.
+// The remote source is optional, params.Remote holds its initial pairs fetched at params.RemoteIndex.
+func NewReloaderWith(newDst func() interface{}, params LoadParams, remote *Remote) (*Reloader, error) {
+	r := &Reloader{
+		newDst:  newDst,
+		params:  params,
+		remote:  remote,
+		index:   params.RemoteIndex,
+		history: NewHistory(HistorySizeDefault),
+	}
+
+	dst := newDst()
+	if err := LoadWith(dst, r.params); err != nil {
+		return nil, err
+	}
+	r.current = dst
+	r.history.Record(dst, SourceInitial)
+
+	return r, nil
+}
+
+// Current returns the last applied config value.
+func (r *Reloader) Current() interface{} {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.current
+}
+
+// OnChange registers a callback called with every newly applied config value.
+func (r *Reloader) OnChange(fn func(interface{})) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.onChange = append(r.onChange, fn)
+}
+
+// OnError registers a callback called when a background reload fails.
+func (r *Reloader) OnError(fn func(error)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.onError = append(r.onError, fn)
+}
+
+// Reload re-reads the config file and applies the new value if it loads successfully.
+func (r *Reloader) Reload() error {
+	r.reloading.Lock()
+	defer r.reloading.Unlock()
+
+	params := r.params
+	if params.Path != "" {
+		file, err := os.ReadFile(params.Path)
+		if err != nil {
+			return fmt.Errorf("unable to read %s file: %w", params.Path, err)
+		}
+		params.File = file
+	}
+
+	return r.load(params, SourceFile)
+}
+
+// History returns the history of the applied config values.
+func (r *Reloader) History() *History {
+	return r.history
+}
+
+// Rollback applies the config value of the snapshot with the version again.
+// The rollback is recorded as a new snapshot and lasts until the next reload.
+func (r *Reloader) Rollback(version int) (Snapshot, error) {
+	r.reloading.Lock()
+	defer r.reloading.Unlock()
+
+	s, ok := r.history.Get(version)
+	if !ok {
+		return Snapshot{}, fmt.Errorf("no config snapshot of version %d", version)
+	}
+
+	return r.apply(s.value, fmt.Sprintf("%s to %d", SourceRollback, version)), nil
+}
+
+func (r *Reloader) load(params LoadParams, source string) error {
+	dst := r.newDst()
+	if err := LoadWith(dst, params); err != nil {
+		return err
+	}
+	r.params = params
+	r.apply(dst, source)
+
+	return nil
+}
+
+func (r *Reloader) apply(dst interface{}, source string) Snapshot {
+	r.mu.Lock()
+	r.current = dst
+	s := r.history.Record(dst, source)
+	onChange := r.onChange
+	r.mu.Unlock()
+
+	for _, fn := range onChange {
+		fn(dst)
+	}
+
+	return s
+}
+
+func (r *Reloader) fail(err error) {
+	r.mu.RLock()
+	onError := r.onError
+	r.mu.RUnlock()
+
+	for _, fn := range onError {
+		fn(err)
+	}
+}
+
+// Run long-polls the remote source and reloads the config on every change until ctx is done.
+// Without a remote source it just waits for ctx. Errors are reported to OnError callbacks
+// and polling is retried, so Run fits a grace.RunGroup actor.
+func (r *Reloader) Run(ctx context.Context) error {
+	if r.remote == nil {
+		<-ctx.Done()
+		return nil
+	}
+
+	for {
+		pairs, index, err := r.remote.Watch(ctx, r.index)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			r.fail(err)
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(reloadRetryDefault):
+			}
+			continue
+		}
+		if index == r.index {
+			continue
+		}
+		r.index = index
+
+		r.reloading.Lock()
+		params := r.params
+		params.Remote = pairs
+		if err = r.load(params, SourceRemote); err != nil {
+			r.fail(fmt.Errorf("reload error: %w", err))
+		}
+		r.reloading.Unlock()
+	}
+}
@@ -0,0 +1,179 @@
+package cfg
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/sorohimm/utils/cfg/kv"
+)
+
+const RemoteTimeoutDefault = 5 * time.Second
+
+// KV is a key-value config store such as Consul or etcd.
+type KV interface {
+	// Get returns all the pairs under the prefix and the store index of their latest change.
+	// If waitIndex is not zero, Get blocks until the index differs from it or ctx is done (long polling).
+	Get(ctx context.Context, prefix string, waitIndex uint64) (map[string]string, uint64, error)
+}
+
+// Remote pulls config pairs from a KV store under a key prefix. Keys are split by "/"
+// into YAML paths relative to the prefix (e.g. "app/http/port" with the "app" prefix
+// sets http.port) and values are parsed as YAML. The pairs are merged between
+// the YAML file and env layers.
+//
+// The last fetched pairs are stored at CachePath, so the service still starts
+// with them when the store is unreachable. Failing to write the cache does not fail
+// Fetch or Watch, the error is passed to OnCacheError if it is set.
+type Remote struct {
+	KV           KV
+	Prefix       string
+	CachePath    string
+	Timeout      time.Duration
+	OnCacheError func(error)
+}
+
+type remoteCache struct {
+	Index uint64            `json:"index"`
+	Pairs map[string]string `json:"pairs"`
+}
+
+// Fetch returns the current pairs, falling back to the cache if the store is unreachable.
+func (r *Remote) Fetch(ctx context.Context) (map[string]string, uint64, error) {
+	timeout := r.Timeout
+	if timeout == 0 {
+		timeout = RemoteTimeoutDefault
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	pairs, index, err := r.KV.Get(ctx, r.Prefix, 0)
+	if err != nil {
+		cached, cacheErr := r.readCache()
+		if cacheErr != nil {
+			return nil, 0, fmt.Errorf("remote get error: %w (%s)", err, cacheErr.Error())
+		}
+		return cached.Pairs, cached.Index, nil
+	}
+
+	return r.store(pairs, index)
+}
+
+// Watch blocks until the pairs change from the index or ctx is done and returns the new pairs.
+func (r *Remote) Watch(ctx context.Context, index uint64) (map[string]string, uint64, error) {
+	pairs, newIndex, err := r.KV.Get(ctx, r.Prefix, index)
+	if err != nil {
+		return nil, 0, fmt.Errorf("remote watch error: %w", err)
+	}
+
+	return r.store(pairs, newIndex)
+}
+
+func (r *Remote) store(pairs map[string]string, index uint64) (map[string]string, uint64, error) {
+	pairs = r.trimPrefix(pairs)
+	if r.CachePath == "" {
+		return pairs, index, nil
+	}
+
+	if err := r.writeCache(&remoteCache{Index: index, Pairs: pairs}); err != nil && r.OnCacheError != nil {
+		r.OnCacheError(err)
+	}
+
+	return pairs, index, nil
+}
+
+func (r *Remote) writeCache(cached *remoteCache) error {
+	data, err := json.Marshal(cached)
+	if err != nil {
+		return fmt.Errorf("marshal remote cache error: %w", err)
+	}
+	if err = os.WriteFile(r.CachePath, data, 0o600); err != nil {
+		return fmt.Errorf("write remote cache error: %w", err)
+	}
+
+	return nil
+}
+
+func (r *Remote) readCache() (*remoteCache, error) {
+	if r.CachePath == "" {
+		return nil, fmt.Errorf("no remote cache")
+	}
+	data, err := os.ReadFile(r.CachePath)
+	if err != nil {
+		return nil, fmt.Errorf("read remote cache error: %w", err)
+	}
+
+	var cached remoteCache
+	if err = json.Unmarshal(data, &cached); err != nil {
+		return nil, fmt.Errorf("unmarshal remote cache error: %w", err)
+	}
+
+	return &cached, nil
+}
+
+// trimPrefix makes the keys relative to the prefix and drops directory entries
+// and keys outside of the prefix.
+func (r *Remote) trimPrefix(pairs map[string]string) map[string]string {
+	prefix := strings.Trim(r.Prefix, "/")
+	res := make(map[string]string, len(pairs))
+	for k, v := range pairs {
+		if !kv.HasPrefix(k, prefix) {
+			continue
+		}
+		k = strings.Trim(strings.TrimPrefix(strings.TrimLeft(k, "/"), prefix), "/")
+		if k == "" || v == "" {
+			continue
+		}
+		res[k] = v
+	}
+
+	return res
+}
+
+// remoteNode builds a mapping node from the pairs, so it can be merged into the document.
+func remoteNode(pairs map[string]string) (*yaml.Node, error) {
+	keys := make([]string, 0, len(pairs))
+	for k := range pairs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	root := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	for _, k := range keys {
+		var doc yaml.Node
+		if err := yaml.Unmarshal([]byte(pairs[k]), &doc); err != nil {
+			return nil, fmt.Errorf("remote key %s: %w", k, err)
+		}
+		if len(doc.Content) == 0 {
+			continue
+		}
+
+		node := root
+		path := strings.Split(k, "/")
+		for _, p := range path[:len(path)-1] {
+			idx := keyIndex(node, p)
+			if idx < 0 {
+				node.Content = append(node.Content,
+					&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: p},
+					&yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"})
+				idx = len(node.Content) - 2
+			}
+			node = node.Content[idx+1]
+			if node.Kind != yaml.MappingNode {
+				return nil, fmt.Errorf("remote key %s: %s is not a mapping", k, p)
+			}
+		}
+		merge(node, &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Content: []*yaml.Node{
+			{Kind: yaml.ScalarNode, Tag: "!!str", Value: path[len(path)-1]},
+			doc.Content[0],
+		}})
+	}
+
+	return root, nil
+}
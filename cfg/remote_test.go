@@ -0,0 +1,122 @@
+package cfg
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/sorohimm/utils/cfg/kv"
+)
+
+type remoteCfg struct {
+	Http struct {
+		URL  string `yaml:"url" env:"HTTP_URL"`
+		Port int    `yaml:"port"`
+	} `yaml:"http"`
+	Hosts []string `yaml:"hosts"`
+}
+
+type unreachableKV struct{}
+
+func (unreachableKV) Get(context.Context, string, uint64) (map[string]string, uint64, error) {
+	return nil, 0, errors.New("connection refused")
+}
+
+func TestRemote(t *testing.T) {
+	t.Run("testRemoteLayer", testRemoteLayer)
+	t.Run("testRemoteCache", testRemoteCache)
+	t.Run("testReloaderRun", testReloaderRun)
+}
+
+func testRemoteLayer(t *testing.T) {
+	c := remoteCfg{}
+	err := LoadWith(&c, LoadParams{
+		File: []byte("http:\n  url: http://file\n  port: 80\n"),
+		Remote: map[string]string{
+			"http/url":  "http://remote",
+			"http/port": "8080",
+			"hosts":     "[a, b]",
+		},
+		Environment: map[string]string{"HTTP_URL": "http://env"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "http://env", c.Http.URL)
+	require.Equal(t, 8080, c.Http.Port)
+	require.Equal(t, []string{"a", "b"}, c.Hosts)
+}
+
+func testRemoteCache(t *testing.T) {
+	store := kv.NewMemory()
+	store.Set("app/http/port", "8080")
+	store.Set("other/key", "1")
+	store.Set("app2/http/port", "1")
+
+	cache := filepath.Join(t.TempDir(), "remote.json")
+	r := &Remote{KV: store, Prefix: "app", CachePath: cache}
+	pairs, _, err := r.Fetch(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"http/port": "8080"}, pairs)
+
+	r.KV = unreachableKV{}
+	pairs, _, err = r.Fetch(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"http/port": "8080"}, pairs)
+
+	r.CachePath = ""
+	_, _, err = r.Fetch(context.Background())
+	require.ErrorContains(t, err, "connection refused")
+
+	var cacheErr error
+	r = &Remote{
+		KV:           store,
+		Prefix:       "/app/",
+		CachePath:    filepath.Join(t.TempDir(), "missing", "remote.json"),
+		OnCacheError: func(err error) { cacheErr = err },
+	}
+	pairs, _, err = r.Fetch(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"http/port": "8080"}, pairs)
+	require.ErrorContains(t, cacheErr, "write remote cache error")
+}
+
+func testReloaderRun(t *testing.T) {
+	store := kv.NewMemory()
+	store.Set("app/http/port", "8080")
+	remote := &Remote{KV: store, Prefix: "app"}
+
+	pairs, index, err := remote.Fetch(context.Background())
+	require.NoError(t, err)
+
+	r, err := NewReloaderWith(func() interface{} {
+		return &remoteCfg{}
+	}, LoadParams{Remote: pairs, RemoteIndex: index, Environment: map[string]string{}}, remote)
+	require.NoError(t, err)
+	require.Equal(t, 8080, r.Current().(*remoteCfg).Http.Port)
+
+	changed := make(chan *remoteCfg, 1)
+	r.OnChange(func(c interface{}) {
+		changed <- c.(*remoteCfg)
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- r.Run(ctx)
+	}()
+
+	store.Set("app/http/port", "9090")
+	select {
+	case c := <-changed:
+		require.Equal(t, 9090, c.Http.Port)
+	case <-time.After(time.Second):
+		t.Fatal("config was not reloaded")
+	}
+	require.Equal(t, 9090, r.Current().(*remoteCfg).Http.Port)
+
+	cancel()
+	require.NoError(t, <-done)
+}
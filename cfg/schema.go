@@ -0,0 +1,77 @@
+package cfg
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// versionKey is the top-level YAML key holding the config schema version.
+const versionKey = "version"
+
+// Migration transforms the root mapping node of a config document
+// from the version it is registered for to the next one.
+type Migration func(root *yaml.Node) error
+
+var (
+	migrations   = map[int]Migration{}
+	migrationsMu sync.RWMutex
+)
+
+// RegisterMigration registers a migration of config documents from version `from` to `from+1`.
+// Documents with a top-level `version:` key are migrated step by step before decoding,
+// documents without it are decoded as is.
+//
+// Example usage:
+//
+//	cfg.RegisterMigration(1, func(root *yaml.Node) error {
+//	    return cfg.RenameKey(root, "timeout", "idleTimeout")
+//	})
+func RegisterMigration(from int, m Migration) {
+	migrationsMu.Lock()
+	defer migrationsMu.Unlock()
+	migrations[from] = m
+}
+
+func applyMigrations(root *yaml.Node) error {
+	idx := keyIndex(root, versionKey)
+	if idx < 0 {
+		return nil
+	}
+
+	versionNode := root.Content[idx+1]
+	version, err := strconv.Atoi(versionNode.Value)
+	if err != nil {
+		return fmt.Errorf("line %d: version must be an integer: %w", versionNode.Line, err)
+	}
+
+	migrationsMu.RLock()
+	defer migrationsMu.RUnlock()
+
+	for m, ok := migrations[version]; ok; m, ok = migrations[version] {
+		if err = m(root); err != nil {
+			return fmt.Errorf("migration from version %d error: %w", version, err)
+		}
+		version++
+	}
+	versionNode.Value = strconv.Itoa(version)
+
+	return nil
+}
+
+// RenameKey renames the key of the mapping node, it is a helper for migrations.
+// It does nothing if the key is not set and fails if the new key is already set.
+func RenameKey(node *yaml.Node, old, name string) error {
+	oldIdx := keyIndex(node, old)
+	if oldIdx < 0 {
+		return nil
+	}
+	if keyIndex(node, name) >= 0 {
+		return fmt.Errorf("line %d: unable to rename %q, key %q is already set", node.Content[oldIdx].Line, old, name)
+	}
+	node.Content[oldIdx].Value = name
+
+	return nil
+}
@@ -0,0 +1,147 @@
+package cfg
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// secretFileTag reads a Secret from a file: `password: !file /run/secrets/db_password`
+const secretFileTag = "!file"
+
+// Secret is a secret backed by a byte slice that can be explicitly zeroed.
+// Unlike SafeString it never turns into a plain string by itself: marshalling
+// to JSON, YAML or text and formatting with fmt always produce the masked value.
+//
+// It decodes from YAML (a `!file path` value is read from the file) and from env
+// through encoding.TextUnmarshaler, so `env:"PASSWORD,file"` reads it from a secret file.
+// The YAML document and env strings it is decoded from still live in memory,
+// Secret only guarantees its own copy can be wiped.
+type Secret struct {
+	b []byte
+}
+
+// NewSecret creates a Secret holding a copy of b.
+func NewSecret(b []byte) Secret {
+	return Secret{b: bytes.Clone(b)}
+}
+
+// Bytes returns the secret itself, not a copy. It is zeroed by Zero.
+func (s Secret) Bytes() []byte {
+	return s.b
+}
+
+// Len returns the secret length.
+func (s Secret) Len() int {
+	return len(s.b)
+}
+
+// Zero overwrites the secret with zeros and releases it.
+// Copies of the Secret share the memory, so they are wiped too.
+func (s *Secret) Zero() {
+	for i := range s.b {
+		s.b[i] = 0
+	}
+	s.b = nil
+}
+
+// Equal compares the secrets in constant time.
+func (s Secret) Equal(other Secret) bool {
+	return s.EqualBytes(other.b)
+}
+
+// EqualBytes compares the secret with b in constant time.
+func (s Secret) EqualBytes(b []byte) bool {
+	return subtle.ConstantTimeCompare(s.b, b) == 1
+}
+
+// Masked returns the secret masked the same way as SafeString.
+// The returned string holds the last characters of the secret and, being immutable,
+// cannot be wiped by Zero. Format, MarshalJSON and MarshalText build the masked value
+// in a byte slice instead, prefer them where it matters.
+func (s Secret) Masked() string {
+	return string(s.appendMasked(nil))
+}
+
+// appendMasked appends the masked secret to dst without going through a string.
+func (s Secret) appendMasked(dst []byte) []byte {
+	if len(s.b) == 0 {
+		return dst
+	}
+
+	rem := min(len(s.b)/3, 5)
+	for i := 0; i < 20-rem; i++ {
+		dst = append(dst, '*')
+	}
+
+	return append(dst, s.b[len(s.b)-rem:]...)
+}
+
+func (s Secret) String() string {
+	return s.Masked()
+}
+
+// Format masks the secret for every verb, including %#v.
+func (s Secret) Format(f fmt.State, _ rune) {
+	b := s.appendMasked(make([]byte, 0, 20))
+	_, _ = f.Write(b)
+	clear(b)
+}
+
+func (s Secret) MarshalJSON() ([]byte, error) {
+	b := s.appendMasked(make([]byte, 0, 20))
+	defer clear(b)
+
+	res := make([]byte, 0, len(b)+2)
+	res = append(res, '"')
+	for _, c := range b {
+		switch {
+		case c == '"' || c == '\\':
+			res = append(res, '\\', c)
+		case c < 0x20:
+			res = append(res, fmt.Sprintf(`\u%04x`, c)...)
+		default:
+			res = append(res, c)
+		}
+	}
+
+	return append(res, '"'), nil
+}
+
+// MarshalYAML returns Masked, yaml.v3 has no way to marshal a byte slice as a plain string.
+func (s Secret) MarshalYAML() (interface{}, error) {
+	return s.Masked(), nil
+}
+
+func (s Secret) MarshalText() ([]byte, error) {
+	return s.appendMasked(nil), nil
+}
+
+func (s *Secret) UnmarshalText(text []byte) error {
+	s.Zero()
+	s.b = bytes.Clone(text)
+
+	return nil
+}
+
+func (s *Secret) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind != yaml.ScalarNode {
+		return fmt.Errorf("line %d: secret must be a scalar", node.Line)
+	}
+
+	if node.Tag != secretFileTag {
+		return s.UnmarshalText([]byte(node.Value))
+	}
+
+	b, err := os.ReadFile(node.Value)
+	if err != nil {
+		return fmt.Errorf("line %d: read secret file error: %w", node.Line, err)
+	}
+	s.Zero()
+	s.b = bytes.TrimRight(b, "\r\n")
+
+	return nil
+}
@@ -0,0 +1,72 @@
+package cfg
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+type secretCfg struct {
+	Password Secret `yaml:"password" env:"PASSWORD"`
+	Token    Secret `yaml:"token" env:"TOKEN,file"`
+	Key      Secret `yaml:"key"`
+}
+
+func TestSecret(t *testing.T) {
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "key")
+	tokenFile := filepath.Join(dir, "token")
+	require.NoError(t, os.WriteFile(keyFile, []byte("key-from-file\n"), 0o600))
+	require.NoError(t, os.WriteFile(tokenFile, []byte("token-from-file"), 0o600))
+
+	c := secretCfg{}
+	err := LoadWith(&c, LoadParams{
+		File:        []byte("password: yaml-password\nkey: !file " + keyFile + "\n"),
+		Environment: map[string]string{"TOKEN": tokenFile},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []byte("yaml-password"), c.Password.Bytes())
+	require.Equal(t, []byte("key-from-file"), c.Key.Bytes())
+	require.Equal(t, []byte("token-from-file"), c.Token.Bytes())
+
+	b, err := json.Marshal(c)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"Password":"****************word","Token":"***************-file","Key":"****************file"}`,
+		string(b))
+
+	b, err = yaml.Marshal(c)
+	require.NoError(t, err)
+	require.NotContains(t, string(b), "yaml-password")
+
+	require.NotContains(t, fmt.Sprintf("%v %+v %#v %s", c, c, c, c.Password), "yaml-password")
+
+	escaped := NewSecret([]byte("passwo\"\\\n"))
+	b, err = json.Marshal(escaped)
+	require.NoError(t, err)
+	var masked string
+	require.NoError(t, json.Unmarshal(b, &masked))
+	require.Equal(t, "*****************\"\\\n", masked)
+	require.Equal(t, masked, escaped.Masked())
+
+	require.True(t, c.Password.Equal(NewSecret([]byte("yaml-password"))))
+	require.False(t, c.Password.EqualBytes([]byte("yaml-passwore")))
+
+	other := c.Password
+	c.Password.Zero()
+	require.Zero(t, c.Password.Len())
+	require.Equal(t, make([]byte, len("yaml-password")), other.Bytes())
+}
+
+func TestSecretDiff(t *testing.T) {
+	a := secretCfg{Password: NewSecret([]byte("first-password!"))}
+	b := secretCfg{Password: NewSecret([]byte("other-password!"))}
+	require.Equal(t, []Change{
+		{Path: "password", Old: "***************word!", New: "***************word! (changed)"},
+	}, Diff(&a, &b))
+	require.NotEqual(t, hash(&a), hash(&b))
+}
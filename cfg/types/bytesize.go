@@ -0,0 +1,108 @@
+package types
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ByteSize is a size in bytes written with an optional unit: 512, 10KB, 64MiB, 1.5GB.
+// Decimal units (KB, MB, GB, TB) are powers of 1000 and binary ones (KiB, MiB, GiB, TiB) of 1024.
+type ByteSize int64
+
+const (
+	Byte ByteSize = 1
+	KB            = 1000 * Byte
+	MB            = 1000 * KB
+	GB            = 1000 * MB
+	TB            = 1000 * GB
+	KiB           = 1024 * Byte
+	MiB           = 1024 * KiB
+	GiB           = 1024 * MiB
+	TiB           = 1024 * GiB
+)
+
+var byteUnits = map[string]ByteSize{
+	"":    Byte,
+	"b":   Byte,
+	"kb":  KB,
+	"mb":  MB,
+	"gb":  GB,
+	"tb":  TB,
+	"kib": KiB,
+	"mib": MiB,
+	"gib": GiB,
+	"tib": TiB,
+}
+
+// ParseByteSize parses the size, units are case-insensitive.
+func ParseByteSize(s string) (ByteSize, error) {
+	trimmed := strings.TrimSpace(s)
+	i := strings.IndexFunc(trimmed, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	if i < 0 {
+		i = len(trimmed)
+	}
+
+	n, err := strconv.ParseFloat(trimmed[:i], 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid byte size %q", s)
+	}
+	unit, ok := byteUnits[strings.ToLower(strings.TrimSpace(trimmed[i:]))]
+	if !ok {
+		return 0, fmt.Errorf("invalid byte size unit in %q", s)
+	}
+
+	size := n * float64(unit)
+	if size >= math.MaxInt64 { // float64(math.MaxInt64) rounds up to 2^63
+		return 0, fmt.Errorf("byte size %q overflows", s)
+	}
+
+	return ByteSize(size), nil
+}
+
+// Bytes returns the size as an int64.
+func (b ByteSize) Bytes() int64 {
+	return int64(b)
+}
+
+// String returns the size in the largest binary unit it is a whole multiple of.
+func (b ByteSize) String() string {
+	for _, u := range []struct {
+		name string
+		size ByteSize
+	}{{"TiB", TiB}, {"GiB", GiB}, {"MiB", MiB}, {"KiB", KiB}} {
+		if b != 0 && b%u.size == 0 {
+			return strconv.FormatInt(int64(b/u.size), 10) + u.name
+		}
+	}
+
+	return strconv.FormatInt(int64(b), 10) + "B"
+}
+
+func (b ByteSize) MarshalText() ([]byte, error) {
+	return []byte(b.String()), nil
+}
+
+func (b *ByteSize) UnmarshalText(text []byte) error {
+	size, err := ParseByteSize(string(text))
+	if err != nil {
+		return err
+	}
+	*b = size
+
+	return nil
+}
+
+func (b *ByteSize) UnmarshalYAML(node *yaml.Node) error {
+	text, err := scalar(node)
+	if err != nil {
+		return err
+	}
+
+	return lineError(node, b.UnmarshalText(text))
+}
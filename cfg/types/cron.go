@@ -0,0 +1,204 @@
+package types
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// cronSearchLimit bounds the search of the next activation, e.g. for "0 0 30 2 *" that never fires.
+const cronSearchLimit = 5 * 366 * 24 * time.Hour
+
+var cronMacros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+type cronField struct {
+	name     string
+	min, max int
+}
+
+var cronFields = []cronField{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 6},
+}
+
+// Cron is a standard five-field cron expression (minute, hour, day of month, month, day of week)
+// with lists, ranges and steps (e.g. "*/15 9-17 * * 1-5"), or one of the @hourly, @daily,
+// @weekly, @monthly and @yearly macros. Sunday is 0 (7 is accepted as well).
+type Cron struct {
+	expr   string
+	fields [5]uint64 // bit sets of the allowed values
+}
+
+// ParseCron parses and validates the expression.
+func ParseCron(s string) (Cron, error) {
+	expr := strings.TrimSpace(s)
+	spec := expr
+	if macro, ok := cronMacros[spec]; ok {
+		spec = macro
+	}
+
+	parts := strings.Fields(spec)
+	if len(parts) != len(cronFields) {
+		return Cron{}, fmt.Errorf("invalid cron %q: expected 5 fields", s)
+	}
+
+	c := Cron{expr: expr}
+	for i, part := range parts {
+		bits, err := parseCronField(part, cronFields[i])
+		if err != nil {
+			return Cron{}, fmt.Errorf("invalid cron %q: %w", s, err)
+		}
+		c.fields[i] = bits
+	}
+	// 7 is Sunday as well
+	if c.fields[4]&(1<<7) != 0 {
+		c.fields[4] |= 1
+	}
+
+	return c, nil
+}
+
+func parseCronField(s string, f cronField) (uint64, error) {
+	max := f.max
+	if f.name == "day of week" {
+		max = 7
+	}
+
+	var bits uint64
+	for _, item := range strings.Split(s, ",") {
+		rng, stepStr, hasStep := strings.Cut(item, "/")
+		step := 1
+		if hasStep {
+			var err error
+			if step, err = strconv.Atoi(stepStr); err != nil || step < 1 {
+				return 0, fmt.Errorf("invalid %s step %q", f.name, stepStr)
+			}
+		}
+
+		lo, hi := f.min, max
+		if rng != "*" {
+			from, to, isRange := strings.Cut(rng, "-")
+			var err error
+			if lo, err = strconv.Atoi(from); err != nil {
+				return 0, fmt.Errorf("invalid %s %q", f.name, item)
+			}
+			hi = lo
+			if isRange {
+				if hi, err = strconv.Atoi(to); err != nil {
+					return 0, fmt.Errorf("invalid %s %q", f.name, item)
+				}
+			} else if hasStep {
+				hi = max
+			}
+		}
+		if lo < f.min || hi > max || lo > hi {
+			return 0, fmt.Errorf("%s %q is out of %d-%d range", f.name, item, f.min, f.max)
+		}
+
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << v
+		}
+	}
+
+	return bits, nil
+}
+
+// Next returns the first activation time after t, truncated to minutes,
+// or the zero time if the expression never fires.
+func (c Cron) Next(t time.Time) time.Time {
+	if c.expr == "" {
+		return time.Time{}
+	}
+
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.Add(cronSearchLimit)
+	for t.Before(limit) {
+		switch {
+		case !c.has(3, int(t.Month())):
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+		case !c.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+		case !c.has(1, t.Hour()):
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+		case !c.has(0, t.Minute()):
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+
+	return time.Time{}
+}
+
+// dayMatches follows cron: if both day fields are restricted, either of them matches.
+func (c Cron) dayMatches(t time.Time) bool {
+	dom, dow := c.has(2, t.Day()), c.has(4, int(t.Weekday()))
+	domAny, dowAny := c.all(2), c.all(4)
+
+	switch {
+	case domAny && dowAny:
+		return true
+	case domAny:
+		return dow
+	case dowAny:
+		return dom
+	}
+
+	return dom || dow
+}
+
+func (c Cron) has(field, v int) bool {
+	return c.fields[field]&(1<<v) != 0
+}
+
+func (c Cron) all(field int) bool {
+	f := cronFields[field]
+	for v := f.min; v <= f.max; v++ {
+		if !c.has(field, v) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (c Cron) String() string {
+	return c.expr
+}
+
+func (c Cron) MarshalText() ([]byte, error) {
+	return []byte(c.expr), nil
+}
+
+func (c *Cron) UnmarshalText(text []byte) error {
+	cron, err := ParseCron(string(text))
+	if err != nil {
+		return err
+	}
+	*c = cron
+
+	return nil
+}
+
+func (c *Cron) UnmarshalYAML(node *yaml.Node) error {
+	text, err := scalar(node)
+	if err != nil {
+		return err
+	}
+
+	return lineError(node, c.UnmarshalText(text))
+}
@@ -0,0 +1,57 @@
+package types
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+
+	"gopkg.in/yaml.v3"
+)
+
+// HostPort is a host:port pair, e.g. localhost:8080 or [::1]:443. The host may be empty (":8080").
+type HostPort struct {
+	Host string
+	Port int
+}
+
+// ParseHostPort parses and validates the pair, the port must be in 1-65535.
+func ParseHostPort(s string) (HostPort, error) {
+	host, port, err := net.SplitHostPort(s)
+	if err != nil {
+		return HostPort{}, fmt.Errorf("invalid host:port %q: %w", s, err)
+	}
+
+	p, err := strconv.Atoi(port)
+	if err != nil || p < 1 || p > 65535 {
+		return HostPort{}, fmt.Errorf("invalid port in %q", s)
+	}
+
+	return HostPort{Host: host, Port: p}, nil
+}
+
+func (h HostPort) String() string {
+	return net.JoinHostPort(h.Host, strconv.Itoa(h.Port))
+}
+
+func (h HostPort) MarshalText() ([]byte, error) {
+	return []byte(h.String()), nil
+}
+
+func (h *HostPort) UnmarshalText(text []byte) error {
+	hp, err := ParseHostPort(string(text))
+	if err != nil {
+		return err
+	}
+	*h = hp
+
+	return nil
+}
+
+func (h *HostPort) UnmarshalYAML(node *yaml.Node) error {
+	text, err := scalar(node)
+	if err != nil {
+		return err
+	}
+
+	return lineError(node, h.UnmarshalText(text))
+}
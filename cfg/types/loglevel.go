@@ -0,0 +1,53 @@
+package types
+
+import (
+	"fmt"
+
+	"go.uber.org/zap/zapcore"
+	"gopkg.in/yaml.v3"
+)
+
+// LogLevel is a zap log level name: debug, info, warn, error, dpanic, panic or fatal.
+type LogLevel zapcore.Level
+
+// ParseLogLevel parses the level name, it is case-insensitive.
+func ParseLogLevel(s string) (LogLevel, error) {
+	var l zapcore.Level
+	if err := l.UnmarshalText([]byte(s)); err != nil || s == "" {
+		return 0, fmt.Errorf("invalid log level %q", s)
+	}
+
+	return LogLevel(l), nil
+}
+
+// Level returns the zap level.
+func (l LogLevel) Level() zapcore.Level {
+	return zapcore.Level(l)
+}
+
+func (l LogLevel) String() string {
+	return zapcore.Level(l).String()
+}
+
+func (l LogLevel) MarshalText() ([]byte, error) {
+	return []byte(l.String()), nil
+}
+
+func (l *LogLevel) UnmarshalText(text []byte) error {
+	level, err := ParseLogLevel(string(text))
+	if err != nil {
+		return err
+	}
+	*l = level
+
+	return nil
+}
+
+func (l *LogLevel) UnmarshalYAML(node *yaml.Node) error {
+	text, err := scalar(node)
+	if err != nil {
+		return err
+	}
+
+	return lineError(node, l.UnmarshalText(text))
+}
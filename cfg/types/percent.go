@@ -0,0 +1,57 @@
+package types
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Percent is a percentage in 0-100 written with or without the sign: 25% or 12.5.
+type Percent float64
+
+// ParsePercent parses and validates the percentage.
+func ParsePercent(s string) (Percent, error) {
+	p, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(s), "%"), 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid percent %q", s)
+	}
+	if !(p >= 0 && p <= 100) { // NaN fails both comparisons
+		return 0, fmt.Errorf("percent %q is out of 0-100 range", s)
+	}
+
+	return Percent(p), nil
+}
+
+// Fraction returns the percentage as a fraction of one, e.g. 0.25 for 25%.
+func (p Percent) Fraction() float64 {
+	return float64(p) / 100
+}
+
+func (p Percent) String() string {
+	return strconv.FormatFloat(float64(p), 'f', -1, 64) + "%"
+}
+
+func (p Percent) MarshalText() ([]byte, error) {
+	return []byte(p.String()), nil
+}
+
+func (p *Percent) UnmarshalText(text []byte) error {
+	percent, err := ParsePercent(string(text))
+	if err != nil {
+		return err
+	}
+	*p = percent
+
+	return nil
+}
+
+func (p *Percent) UnmarshalYAML(node *yaml.Node) error {
+	text, err := scalar(node)
+	if err != nil {
+		return err
+	}
+
+	return lineError(node, p.UnmarshalText(text))
+}
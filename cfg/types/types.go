@@ -0,0 +1,28 @@
+// Package types contains config value types for common infrastructure settings.
+// Every type implements yaml.Unmarshaler and encoding.TextUnmarshaler, so it is decoded
+// by cfg.Load both from YAML and from env, and validates the value while decoding.
+package types
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// scalar returns the value of a scalar node, so YAML decoding can reuse UnmarshalText.
+func scalar(node *yaml.Node) ([]byte, error) {
+	if node.Kind != yaml.ScalarNode {
+		return nil, fmt.Errorf("line %d: expected a scalar value", node.Line)
+	}
+
+	return []byte(node.Value), nil
+}
+
+// lineError adds the YAML line to the decoding error.
+func lineError(node *yaml.Node, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return fmt.Errorf("line %d: %w", node.Line, err)
+}
@@ -0,0 +1,100 @@
+package types
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// URLList is a list of absolute URLs, written as a YAML sequence or a comma-separated string
+// (the env form): http://a:9092,http://b:9092. Passwords are masked by String.
+type URLList []*url.URL
+
+// ParseURLList parses the comma-separated list, every URL must have a scheme and a host.
+func ParseURLList(s string) (URLList, error) {
+	var res URLList
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item == "" {
+			continue
+		}
+		u, err := parseURL(item)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, u)
+	}
+
+	return res, nil
+}
+
+func parseURL(s string) (*url.URL, error) {
+	u, err := url.Parse(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid url: %w", redact(err))
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("invalid url %s: scheme and host are required", u.Redacted())
+	}
+
+	return u, nil
+}
+
+// Strings returns the URLs with passwords.
+func (l URLList) Strings() []string {
+	res := make([]string, 0, len(l))
+	for _, u := range l {
+		res = append(res, u.String())
+	}
+
+	return res
+}
+
+// String returns the comma-separated URLs with passwords masked.
+func (l URLList) String() string {
+	res := make([]string, 0, len(l))
+	for _, u := range l {
+		res = append(res, u.Redacted())
+	}
+
+	return strings.Join(res, ",")
+}
+
+func (l URLList) MarshalText() ([]byte, error) {
+	return []byte(l.String()), nil
+}
+
+func (l *URLList) UnmarshalText(text []byte) error {
+	list, err := ParseURLList(string(text))
+	if err != nil {
+		return err
+	}
+	*l = list
+
+	return nil
+}
+
+func (l *URLList) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		return lineError(node, l.UnmarshalText([]byte(node.Value)))
+	}
+	if node.Kind != yaml.SequenceNode {
+		return fmt.Errorf("line %d: expected a list of urls", node.Line)
+	}
+
+	list := make(URLList, 0, len(node.Content))
+	for _, item := range node.Content {
+		if item.Kind != yaml.ScalarNode {
+			return fmt.Errorf("line %d: expected a url", item.Line)
+		}
+		u, err := parseURL(item.Value)
+		if err != nil {
+			return lineError(item, err)
+		}
+		list = append(list, u)
+	}
+	*l = list
+
+	return nil
+}
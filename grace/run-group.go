@@ -23,7 +23,16 @@ func NewRunGroup() *RunGroup {
 
 // RunGroup is a struct that wraps the oklog/run.Group struct.
 type RunGroup struct {
-	g *run.Group
+	g       *run.Group
+	signals []os.Signal
+}
+
+// ShutdownSignals replaces the signals Run shuts down on, SIGHUP, SIGINT, SIGTERM and SIGQUIT by default,
+// e.g. to leave SIGHUP to an actor reopening log files.
+func (o *RunGroup) ShutdownSignals(sigs ...os.Signal) *RunGroup {
+	o.signals = sigs
+
+	return o
 }
 
 // Add adds a new task to the RunGroup's task list.
@@ -37,7 +46,11 @@ func (o *RunGroup) Run(onShutdown func(error)) error {
 	sigs := make(chan os.Signal, 1)
 	allDone := make(chan struct{})
 	o.g.Add(func() error {
-		signal.Notify(sigs, syscall.SIGHUP, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
+		signals := o.signals
+		if len(signals) == 0 {
+			signals = []os.Signal{syscall.SIGHUP, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT}
+		}
+		signal.Notify(sigs, signals...)
 		for {
 			select {
 			case <-sigs:
@@ -15,7 +15,7 @@ func TestNewRunGroup(t *testing.T) {
 	}{
 		{
 			name: "ok",
-			want: &RunGroup{&run.Group{}},
+			want: &RunGroup{g: &run.Group{}},
 		},
 	}
 	for _, tt := range tests {
@@ -0,0 +1,323 @@
+package log
+
+import (
+	"bufio"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// Overflow policies of AsyncConfig.Overflow.
+const (
+	// OverflowBlock makes writers wait for room in the queue
+	OverflowBlock = "block"
+	// OverflowDropNewest drops the entries that do not fit into the queue
+	OverflowDropNewest = "dropNewest"
+	// OverflowDropDebug drops debug entries once the queue is three quarters full
+	// and other entries that do not fit into it
+	OverflowDropDebug = "dropDebug"
+)
+
+const (
+	asyncQueueSizeDefault     = 1024
+	asyncFlushIntervalDefault = time.Second
+	asyncBufferSize           = 256 * 1024
+)
+
+// AsyncConfig enables asynchronous writing: entries are encoded by the logging goroutine
+// and queued, a background goroutine writes them to the outputs in batches flushed
+// every FlushInterval. Entries above the error level are flushed immediately.
+type AsyncConfig struct {
+	Enabled bool `yaml:"enabled" env:"ENABLED"`
+	// QueueSize is the number of entries queued, 1024 if zero
+	QueueSize int `yaml:"queueSize" env:"QUEUE_SIZE" validate:"min=0"`
+	// FlushInterval is 1s if zero
+	FlushInterval time.Duration `yaml:"flushInterval" env:"FLUSH_INTERVAL"`
+	// Overflow is OverflowBlock if empty
+	Overflow string `yaml:"overflow" env:"OVERFLOW" validate:"omitempty,oneof=block dropNewest dropDebug"`
+}
+
+// NewAsyncWriter starts writing the queued entries to the output.
+func NewAsyncWriter(out zapcore.WriteSyncer, c AsyncConfig) *AsyncWriter {
+	if c.QueueSize <= 0 {
+		c.QueueSize = asyncQueueSizeDefault
+	}
+	if c.FlushInterval <= 0 {
+		c.FlushInterval = asyncFlushIntervalDefault
+	}
+	if c.Overflow == "" {
+		c.Overflow = OverflowBlock
+	}
+
+	w := &AsyncWriter{
+		out:      out,
+		buf:      bufio.NewWriterSize(out, asyncBufferSize),
+		overflow: c.Overflow,
+		queue:    make(chan asyncEntry, c.QueueSize),
+		flushes:  make(chan chan error),
+		stop:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go w.loop(c.FlushInterval)
+
+	return w
+}
+
+// AsyncWriter writes encoded entries to an output from a bounded queue and counts
+// the entries dropped by the overflow policy. Once closed it writes synchronously,
+// so entries logged during shutdown are not lost.
+type AsyncWriter struct {
+	out      zapcore.WriteSyncer
+	overflow string
+	queue    chan asyncEntry
+	flushes  chan chan error
+
+	mu  sync.Mutex // guards buf
+	buf *bufio.Writer
+
+	closeMu sync.RWMutex // excludes enqueueing while closing
+	closed  bool
+
+	stop      chan struct{}
+	stopped   chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+	doneOnce  sync.Once
+
+	dropped [zapcore.FatalLevel - zapcore.DebugLevel + 1]atomic.Uint64
+}
+
+type asyncEntry struct {
+	level zapcore.Level
+	data  []byte
+}
+
+func (w *AsyncWriter) enqueue(level zapcore.Level, data []byte) error {
+	w.closeMu.RLock()
+	defer w.closeMu.RUnlock()
+
+	if w.closed {
+		return w.writeOut(data)
+	}
+
+	e := asyncEntry{level: level, data: data}
+	switch {
+	case w.overflow == OverflowBlock:
+		w.queue <- e
+		return nil
+	case w.overflow == OverflowDropDebug && level <= zapcore.DebugLevel && len(w.queue) >= cap(w.queue)*3/4:
+		w.drop(level)
+		return nil
+	}
+
+	select {
+	case w.queue <- e:
+	default:
+		w.drop(level)
+	}
+
+	return nil
+}
+
+func (w *AsyncWriter) drop(level zapcore.Level) {
+	if level < zapcore.DebugLevel {
+		level = zapcore.DebugLevel
+	}
+	if level > zapcore.FatalLevel {
+		level = zapcore.FatalLevel
+	}
+	w.dropped[level-zapcore.DebugLevel].Add(1)
+}
+
+func (w *AsyncWriter) write(data []byte) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	_, err := w.buf.Write(data)
+
+	return err
+}
+
+// writeOut writes the data to the output directly after the buffered entries,
+// so nothing written after Close is left in the buffer.
+func (w *AsyncWriter) writeOut(data []byte) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if err := w.buf.Flush(); err != nil {
+		return err
+	}
+	_, err := w.out.Write(data)
+
+	return err
+}
+
+func (w *AsyncWriter) flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if err := w.buf.Flush(); err != nil {
+		return err
+	}
+
+	return w.out.Sync()
+}
+
+func (w *AsyncWriter) loop(interval time.Duration) {
+	defer close(w.stopped)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case e := <-w.queue:
+			_ = w.write(e.data)
+		case <-ticker.C:
+			_ = w.flush()
+		case reply := <-w.flushes:
+			w.drain()
+			reply <- w.flush()
+		case <-w.stop:
+			w.drain()
+			_ = w.flush()
+			return
+		}
+	}
+}
+
+// drain writes the queued entries.
+func (w *AsyncWriter) drain() {
+	for {
+		select {
+		case e := <-w.queue:
+			_ = w.write(e.data)
+		default:
+			return
+		}
+	}
+}
+
+// Sync writes the queued entries and flushes the output.
+func (w *AsyncWriter) Sync() error {
+	reply := make(chan error, 1)
+	select {
+	case w.flushes <- reply:
+		return <-reply
+	case <-w.stopped:
+		return w.flush()
+	}
+}
+
+// Close writes the queued entries, flushes the output and stops the background goroutine,
+// the entries written afterwards are written synchronously.
+func (w *AsyncWriter) Close() error {
+	w.closeOnce.Do(func() {
+		w.closeMu.Lock()
+		w.closed = true
+		w.closeMu.Unlock()
+
+		close(w.stop)
+	})
+	<-w.stopped
+
+	return w.flush()
+}
+
+// Dropped returns the number of the entries dropped by the overflow policy.
+func (w *AsyncWriter) Dropped() uint64 {
+	var n uint64
+	for i := range w.dropped {
+		n += w.dropped[i].Load()
+	}
+
+	return n
+}
+
+// DroppedByLevel returns the number of the dropped entries of each level that had any.
+func (w *AsyncWriter) DroppedByLevel() map[zapcore.Level]uint64 {
+	res := make(map[zapcore.Level]uint64)
+	for i := range w.dropped {
+		if n := w.dropped[i].Load(); n > 0 {
+			res[zapcore.DebugLevel+zapcore.Level(i)] = n
+		}
+	}
+
+	return res
+}
+
+// Run is the actor of grace.RunGroup.Add with Interrupt: it waits for the interrupt
+// and closes the writer, so the queued entries are written on exit:
+//
+//	g.Add(logger.Async().Run, logger.Async().Interrupt)
+func (w *AsyncWriter) Run() error {
+	<-w.done
+
+	return w.Close()
+}
+
+// Interrupt stops Run.
+func (w *AsyncWriter) Interrupt(error) {
+	w.doneOnce.Do(func() {
+		close(w.done)
+	})
+}
+
+// asyncCore encodes entries like the zapcore.NewCore one and queues them to the AsyncWriter.
+type asyncCore struct {
+	zapcore.LevelEnabler
+	enc zapcore.Encoder
+	w   *AsyncWriter
+}
+
+func newAsyncCore(enc zapcore.Encoder, w *AsyncWriter, enab zapcore.LevelEnabler) zapcore.Core {
+	return &asyncCore{LevelEnabler: enab, enc: enc, w: w}
+}
+
+func (c *asyncCore) With(fields []zapcore.Field) zapcore.Core {
+	enc := c.enc.Clone()
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+
+	return &asyncCore{LevelEnabler: c.LevelEnabler, enc: enc, w: c.w}
+}
+
+func (c *asyncCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+
+	return ce
+}
+
+func (c *asyncCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	buf, err := c.enc.EncodeEntry(ent, fields)
+	if err != nil {
+		return err
+	}
+	data := append([]byte(nil), buf.Bytes()...)
+	buf.Free()
+
+	if err := c.w.enqueue(ent.Level, data); err != nil {
+		return err
+	}
+	if ent.Level > zapcore.ErrorLevel {
+		// the process is likely to crash, see zapcore.NewCore
+		return c.Sync()
+	}
+
+	return nil
+}
+
+func (c *asyncCore) Sync() error {
+	return c.w.Sync()
+}
+
+// Async returns the asynchronous writer of the logger, nil if it writes synchronously.
+func (o *Zap) Async() *AsyncWriter {
+	return o.async
+}
@@ -0,0 +1,138 @@
+package log
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// syncBuffer is a zapcore.WriteSyncer safe for concurrent use.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) Sync() error {
+	return nil
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.String()
+}
+
+func TestAsync(t *testing.T) {
+	logger, lines := newFileZap(t, Config{
+		DisableCaller: true,
+		Async:         AsyncConfig{Enabled: true, QueueSize: 8, FlushInterval: time.Hour},
+	})
+	require.NotNil(t, logger.Async())
+
+	for i := 0; i < 100; i++ {
+		logger.Info("entry", zap.Int("i", i))
+	}
+
+	got := lines()
+	require.Len(t, got, 100)
+	for i, line := range got {
+		require.Contains(t, line, fmt.Sprintf(`"i":%d`, i))
+	}
+	require.Zero(t, logger.Async().Dropped())
+}
+
+// stall makes the writer take one entry from the queue and wait, so the queue fills up.
+func stall(t *testing.T, w *AsyncWriter) func() {
+	t.Helper()
+
+	w.mu.Lock()
+	require.NoError(t, w.enqueue(zapcore.InfoLevel, []byte("stalled\n")))
+	require.Eventually(t, func() bool { return len(w.queue) == 0 }, time.Second, time.Millisecond)
+
+	return w.mu.Unlock
+}
+
+func TestAsyncOverflow(t *testing.T) {
+	out := &syncBuffer{}
+	w := NewAsyncWriter(out, AsyncConfig{QueueSize: 2, Overflow: OverflowDropNewest})
+
+	release := stall(t, w)
+	for i := 0; i < 4; i++ {
+		require.NoError(t, w.enqueue(zapcore.WarnLevel, []byte(fmt.Sprintf("%d\n", i))))
+	}
+	release()
+
+	require.NoError(t, w.Sync())
+	require.Equal(t, "stalled\n0\n1\n", out.String())
+	require.Equal(t, uint64(2), w.Dropped())
+	require.Equal(t, map[zapcore.Level]uint64{zapcore.WarnLevel: 2}, w.DroppedByLevel())
+}
+
+func TestAsyncOverflowDebug(t *testing.T) {
+	out := &syncBuffer{}
+	w := NewAsyncWriter(out, AsyncConfig{QueueSize: 4, Overflow: OverflowDropDebug})
+
+	release := stall(t, w)
+	for _, l := range []zapcore.Level{zapcore.InfoLevel, zapcore.DebugLevel, zapcore.InfoLevel, zapcore.InfoLevel, zapcore.DebugLevel, zapcore.ErrorLevel, zapcore.InfoLevel} {
+		require.NoError(t, w.enqueue(l, []byte(l.String()+"\n")))
+	}
+	release()
+
+	require.NoError(t, w.Sync())
+	require.Equal(t, "stalled\ninfo\ndebug\ninfo\ninfo\n", out.String())
+	require.Equal(t, map[zapcore.Level]uint64{zapcore.DebugLevel: 1, zapcore.ErrorLevel: 1, zapcore.InfoLevel: 1}, w.DroppedByLevel())
+}
+
+func TestAsyncRun(t *testing.T) {
+	out := &syncBuffer{}
+	w := NewAsyncWriter(out, AsyncConfig{FlushInterval: time.Hour})
+
+	errs := make(chan error, 1)
+	go func() { errs <- w.Run() }()
+
+	require.NoError(t, w.enqueue(zapcore.InfoLevel, []byte("queued\n")))
+	w.Interrupt(nil)
+	require.NoError(t, <-errs)
+	require.Equal(t, "queued\n", out.String())
+
+	// written synchronously once closed
+	require.NoError(t, w.enqueue(zapcore.InfoLevel, []byte("late\n")))
+	require.True(t, strings.HasSuffix(out.String(), "late\n"))
+	require.NoError(t, w.Close())
+}
+
+func TestAsyncAfterClose(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "out.log")
+	logger, err := New(Config{
+		OutputPaths:   []string{out},
+		DisableCaller: true,
+		Async:         AsyncConfig{Enabled: true, FlushInterval: time.Hour},
+	})
+	require.NoError(t, err)
+
+	logger.Info("before close")
+	require.NoError(t, logger.Async().Close())
+	logger.Info("after close")
+
+	data, err := os.ReadFile(out)
+	require.NoError(t, err)
+	require.Contains(t, string(data), `"msg":"before close"`)
+	require.Contains(t, string(data), `"msg":"after close"`)
+}
@@ -0,0 +1,315 @@
+// Package audit writes audit events (who did what to which resource) to a dedicated sink,
+// apart from the application logs: events are never sampled or dropped, every event is written
+// and synced before Log returns, and the entries are hash chained, so a removed, reordered
+// or modified entry is detected by Verify.
+//
+// Without a key the chain is plain SHA-256, so whoever can write the file can also rewrite
+// the whole chain after a change. Set Params.Key to chain the entries with HMAC-SHA256 and keep
+// the key away from the hosts writing the log, or anchor the hash of the last entry elsewhere
+// (e.g. send it to another system periodically) and compare it on verification.
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/sorohimm/utils/log"
+)
+
+// Outcomes of an event.
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+	OutcomeDenied  = "denied"
+)
+
+// Event is an audit event.
+type Event struct {
+	Actor    string
+	Action   string
+	Resource string
+	// Outcome is one of OutcomeSuccess, OutcomeFailure and OutcomeDenied
+	Outcome string
+	// RequestId is taken from the context if empty (see log.CtxWithRequestId)
+	RequestId string
+	Details   map[string]string
+}
+
+func (e Event) validate() error {
+	var errs []error
+	if e.Actor == "" {
+		errs = append(errs, errors.New("actor is required"))
+	}
+	if e.Action == "" {
+		errs = append(errs, errors.New("action is required"))
+	}
+	if e.Resource == "" {
+		errs = append(errs, errors.New("resource is required"))
+	}
+	switch e.Outcome {
+	case OutcomeSuccess, OutcomeFailure, OutcomeDenied:
+	default:
+		errs = append(errs, fmt.Errorf("invalid outcome %q", e.Outcome))
+	}
+
+	return errors.Join(errs...)
+}
+
+func (e Event) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("actor", e.Actor)
+	enc.AddString("action", e.Action)
+	enc.AddString("resource", e.Resource)
+	enc.AddString("outcome", e.Outcome)
+	if e.RequestId != "" {
+		enc.AddString("request_id", e.RequestId)
+	}
+	if len(e.Details) > 0 {
+		return enc.AddObject("details", details(e.Details))
+	}
+
+	return nil
+}
+
+type details map[string]string
+
+func (d details) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	keys := make([]string, 0, len(d))
+	for k := range d {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		enc.AddString(k, d[k])
+	}
+
+	return nil
+}
+
+// Params of the Logger.
+type Params struct {
+	// Key chains the entries with HMAC-SHA256 instead of SHA-256 if set,
+	// Verify needs the same key
+	Key []byte
+}
+
+// New creates a Logger writing to out, starting a new hash chain.
+func New(out zapcore.WriteSyncer, p Params) *Logger {
+	return &Logger{out: out, enc: newEncoder(), now: time.Now, key: p.Key}
+}
+
+// Open creates a Logger appending to the file, the chain continues from the last entry of the file.
+// The existing entries are verified first, the error wraps ErrTampered if the chain is broken.
+// A torn last line left by a crash in the middle of a write is cut off: its event was never logged.
+func Open(path string, p Params) (*Logger, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("open audit log error: %w", err)
+	}
+
+	c, err := resume(f, p.Key)
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+
+	l := New(f, p)
+	l.seq, l.prev, l.close = c.seq, c.prev, f.Close
+
+	return l, nil
+}
+
+// Logger writes audit events synchronously.
+type Logger struct {
+	mu    sync.Mutex
+	out   zapcore.WriteSyncer
+	enc   zapcore.Encoder
+	now   func() time.Time
+	close func() error
+	key   []byte
+
+	seq    uint64
+	prev   string // hash of the previous entry
+	broken error  // the error of a write leaving a torn line
+}
+
+func newEncoder() zapcore.Encoder {
+	return zapcore.NewJSONEncoder(zapcore.EncoderConfig{
+		TimeKey:        "ts",
+		MessageKey:     zapcore.OmitKey,
+		LevelKey:       zapcore.OmitKey,
+		NameKey:        zapcore.OmitKey,
+		CallerKey:      zapcore.OmitKey,
+		FunctionKey:    zapcore.OmitKey,
+		StacktraceKey:  zapcore.OmitKey,
+		LineEnding:     "\n",
+		EncodeTime:     zapcore.RFC3339NanoTimeEncoder,
+		EncodeDuration: zapcore.StringDurationEncoder,
+	})
+}
+
+// Log writes the event and syncs the sink. The entry is
+//
+//	{"ts":"...","seq":1,"prev":"...","event":{"actor":"...",...},"hash":"..."}
+//
+// where hash is the hex SHA-256 (HMAC-SHA256 with Params.Key) of the entry up to the hash field,
+// closed with "}", and prev is the hash of the previous entry. Entries longer than 1 MiB
+// are rejected, as Verify does not read them.
+//
+// The entry is part of the chain once it is written, even if the sync fails afterwards.
+// A write failing after a part of the entry is written leaves a torn line, so the Logger
+// refuses to log after it (Open cuts the torn line off).
+func (l *Logger) Log(ctx context.Context, e Event) error {
+	if e.RequestId == "" {
+		e.RequestId = log.RequestIdFromContext(ctx)
+	}
+	if err := e.validate(); err != nil {
+		return fmt.Errorf("invalid audit event: %w", err)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.broken != nil {
+		return fmt.Errorf("audit log is broken by a torn write: %w", l.broken)
+	}
+
+	buf, err := l.enc.EncodeEntry(zapcore.Entry{Time: l.now()}, []zap.Field{
+		zap.Uint64("seq", l.seq+1),
+		zap.String("prev", l.prev),
+		zap.Object("event", e),
+	})
+	if err != nil {
+		return fmt.Errorf("encode audit event error: %w", err)
+	}
+	defer buf.Free()
+
+	content := buf.Bytes()[:buf.Len()-len("}\n")]
+	hash := digest(l.key, content)
+	line := make([]byte, 0, len(content)+len(hash)+12)
+	line = append(line, content...)
+	line = append(line, `,"hash":"`...)
+	line = append(line, hash...)
+	line = append(line, "\"}\n"...)
+	if len(line) > maxLine {
+		return fmt.Errorf("audit event of %d bytes exceeds the limit of %d bytes", len(line), maxLine)
+	}
+
+	if n, err := l.out.Write(line); err != nil {
+		if n > 0 {
+			l.broken = err
+		}
+		return fmt.Errorf("write audit event error: %w", err)
+	}
+	l.seq, l.prev = l.seq+1, hash
+
+	if err := l.out.Sync(); err != nil {
+		return fmt.Errorf("sync audit log error: %w", err)
+	}
+
+	return nil
+}
+
+// Close closes the file of a Logger created by Open.
+func (l *Logger) Close() error {
+	if l.close == nil {
+		return nil
+	}
+
+	return l.close()
+}
+
+// digest returns the hash of the entry content without the closing brace.
+func digest(key, content []byte) string {
+	h := sha256.New()
+	if key != nil {
+		h = hmac.New(sha256.New, key)
+	}
+	_, _ = h.Write(content)
+	_, _ = h.Write([]byte{'}'})
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+type chainEntry struct {
+	Seq  uint64 `json:"seq"`
+	Prev string `json:"prev"`
+	Hash string `json:"hash"`
+}
+
+// chain checks that the entries follow each other.
+type chain struct {
+	key  []byte
+	seq  uint64
+	prev string // hash of the last entry
+}
+
+// next checks the entry line and makes it the last one.
+func (c *chain) next(b []byte) error {
+	var e chainEntry
+	if err := json.Unmarshal(b, &e); err != nil {
+		return fmt.Errorf("%w: invalid entry: %v", ErrTampered, err)
+	}
+	// the line must end right after the hash, anything appended to it is not covered by the hash
+	i := bytes.LastIndex(b, hashSuffix)
+	if i < 0 || string(b[i+len(hashSuffix):]) != digest(c.key, b[:i])+`"}` {
+		return fmt.Errorf("%w: hash mismatch", ErrTampered)
+	}
+	if e.Seq != c.seq+1 || e.Prev != c.prev {
+		return fmt.Errorf("%w: entry %d does not follow entry %d", ErrTampered, e.Seq, c.seq)
+	}
+	c.seq, c.prev = e.Seq, e.Hash
+
+	return nil
+}
+
+// resume verifies the entries of the file and truncates a torn last line, that is one
+// without a line ending, returning the chain to continue.
+func resume(f *os.File, key []byte) (chain, error) {
+	c := chain{key: key}
+
+	var start, end int64
+	torn := false
+	s := bufio.NewScanner(f)
+	s.Buffer(nil, maxLine)
+	s.Split(func(data []byte, atEOF bool) (int, []byte, error) {
+		advance, token, err := bufio.ScanLines(data, atEOF)
+		if advance > 0 {
+			start, end = end, end+int64(advance)
+			torn = data[advance-1] != '\n'
+		}
+		return advance, token, err
+	})
+	for line := 1; s.Scan(); line++ {
+		if torn {
+			if err := f.Truncate(start); err != nil {
+				return chain{}, fmt.Errorf("truncate torn audit entry error: %w", err)
+			}
+			break
+		}
+		if len(s.Bytes()) == 0 {
+			continue
+		}
+		if err := c.next(s.Bytes()); err != nil {
+			return chain{}, fmt.Errorf("line %d: %w", line, err)
+		}
+	}
+	if err := s.Err(); err != nil {
+		return chain{}, fmt.Errorf("read audit log error: %w", err)
+	}
+
+	return c, nil
+}
@@ -0,0 +1,254 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/sorohimm/utils/log"
+)
+
+func writeEvents(t *testing.T, l *Logger, n int) {
+	t.Helper()
+
+	ctx := log.CtxWithRequestId(context.Background(), "r1")
+	for i := 0; i < n; i++ {
+		require.NoError(t, l.Log(ctx, Event{
+			Actor:    "alice",
+			Action:   "user.delete",
+			Resource: "user/42",
+			Outcome:  OutcomeSuccess,
+			Details:  map[string]string{"reason": "gdpr", "ticket": "T-1"},
+		}))
+	}
+}
+
+func TestLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+
+	l, err := Open(path, Params{})
+	require.NoError(t, err)
+	writeEvents(t, l, 2)
+	require.NoError(t, l.Close())
+
+	// the chain continues after reopening
+	l, err = Open(path, Params{})
+	require.NoError(t, err)
+	writeEvents(t, l, 1)
+	require.NoError(t, l.Close())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 3)
+
+	var entry map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(lines[2]), &entry))
+	require.Equal(t, float64(3), entry["seq"])
+	require.Equal(t, map[string]interface{}{
+		"actor":      "alice",
+		"action":     "user.delete",
+		"resource":   "user/42",
+		"outcome":    "success",
+		"request_id": "r1",
+		"details":    map[string]interface{}{"reason": "gdpr", "ticket": "T-1"},
+	}, entry["event"])
+	require.Contains(t, entry, "ts")
+
+	n, err := Verify(bytes.NewReader(data), nil)
+	require.NoError(t, err)
+	require.Equal(t, 3, n)
+}
+
+func TestOpenTorn(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+
+	l, err := Open(path, Params{})
+	require.NoError(t, err)
+	writeEvents(t, l, 2)
+	require.NoError(t, l.Close())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, append(data, `{"ts":"2024-01-01T00:00:00Z","seq":3,"pr`...), 0o600))
+
+	// the torn line of a crashed write is cut off and the chain continues
+	l, err = Open(path, Params{})
+	require.NoError(t, err)
+	writeEvents(t, l, 1)
+	require.NoError(t, l.Close())
+
+	data, err = os.ReadFile(path)
+	require.NoError(t, err)
+	n, err := Verify(bytes.NewReader(data), nil)
+	require.NoError(t, err)
+	require.Equal(t, 3, n)
+
+	// a broken chain is not continued
+	require.NoError(t, os.WriteFile(path, bytes.Replace(data, []byte("alice"), []byte("mallory"), 1), 0o600))
+	_, err = Open(path, Params{})
+	require.ErrorIs(t, err, ErrTampered)
+	require.ErrorContains(t, err, "line 1:")
+}
+
+func TestLogKey(t *testing.T) {
+	key := []byte("secret")
+	out := &syncBuffer{}
+	writeEvents(t, New(out, Params{Key: key}), 2)
+
+	n, err := Verify(bytes.NewReader(out.Bytes()), key)
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+
+	_, err = Verify(bytes.NewReader(out.Bytes()), nil)
+	require.ErrorIs(t, err, ErrTampered)
+	_, err = Verify(bytes.NewReader(out.Bytes()), []byte("other"))
+	require.ErrorIs(t, err, ErrTampered)
+}
+
+func TestLogTooLarge(t *testing.T) {
+	out := &syncBuffer{}
+	l := New(out, Params{})
+	err := l.Log(context.Background(), Event{
+		Actor:    "alice",
+		Action:   "user.delete",
+		Resource: "user/42",
+		Outcome:  OutcomeSuccess,
+		Details:  map[string]string{"dump": strings.Repeat("x", maxLine)},
+	})
+	require.ErrorContains(t, err, "exceeds the limit")
+	require.Zero(t, out.Len())
+
+	// the chain is not advanced by the rejected event
+	writeEvents(t, l, 1)
+	n, err := Verify(bytes.NewReader(out.Bytes()), nil)
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+}
+
+func TestLogInvalid(t *testing.T) {
+	l := New(&syncBuffer{}, Params{})
+	err := l.Log(context.Background(), Event{Actor: "alice", Outcome: "maybe"})
+	require.ErrorContains(t, err, "action is required")
+	require.ErrorContains(t, err, "resource is required")
+	require.ErrorContains(t, err, `invalid outcome "maybe"`)
+}
+
+func TestLogWriteErrors(t *testing.T) {
+	// the entry is in the sink even if the sync fails, so the chain goes on
+	out := &failingSink{syncErr: errors.New("sync failed")}
+	l := New(out, Params{})
+	for i := 0; i < 2; i++ {
+		require.ErrorContains(t, l.Log(context.Background(), testEvent), "sync failed")
+	}
+	n, err := Verify(bytes.NewReader(out.Bytes()), nil)
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+
+	// a write failing before writing anything leaves the log intact
+	out.syncErr, out.writeErr = nil, errors.New("write failed")
+	require.ErrorContains(t, l.Log(context.Background(), testEvent), "write failed")
+	out.writeErr = nil
+	require.NoError(t, l.Log(context.Background(), testEvent))
+
+	// a torn write stops the logger
+	out.writeErr, out.written = errors.New("disk full"), 10
+	require.ErrorContains(t, l.Log(context.Background(), testEvent), "disk full")
+	out.writeErr = nil
+	require.ErrorContains(t, l.Log(context.Background(), testEvent), "audit log is broken by a torn write: disk full")
+
+	n, err = Verify(bytes.NewReader(out.Bytes()), nil)
+	require.ErrorIs(t, err, ErrTampered)
+	require.Equal(t, 3, n)
+}
+
+var testEvent = Event{Actor: "alice", Action: "user.delete", Resource: "user/42", Outcome: OutcomeSuccess}
+
+// failingSink writes the first written bytes of the entry and fails with writeErr if it is set.
+type failingSink struct {
+	bytes.Buffer
+	written  int
+	writeErr error
+	syncErr  error
+}
+
+func (s *failingSink) Write(p []byte) (int, error) {
+	if s.writeErr != nil {
+		n, _ := s.Buffer.Write(p[:s.written])
+		return n, s.writeErr
+	}
+
+	return s.Buffer.Write(p)
+}
+
+func (s *failingSink) Sync() error {
+	return s.syncErr
+}
+
+type syncBuffer struct {
+	bytes.Buffer
+}
+
+func (b *syncBuffer) Sync() error {
+	return nil
+}
+
+func TestVerifyTampered(t *testing.T) {
+	out := &syncBuffer{}
+	writeEvents(t, New(out, Params{}), 3)
+	lines := strings.SplitAfter(strings.TrimSpace(out.String()), "\n")
+
+	cases := map[string]string{
+		"modified":  strings.Replace(out.String(), "alice", "mallory", 1),
+		"removed":   lines[0] + lines[2],
+		"head":      lines[1] + lines[2],
+		"reordered": lines[0] + lines[2] + "\n" + lines[1],
+		"garbage":   out.String() + "not json\n",
+		"trailing":  strings.TrimSuffix(lines[0], "}\n") + `,"event":{"actor":"mallory"}}` + "\n",
+	}
+	for name, data := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := Verify(strings.NewReader(data), nil)
+			require.ErrorIs(t, err, ErrTampered)
+		})
+	}
+}
+
+func TestVerifyCmd(t *testing.T) {
+	dir := t.TempDir()
+	valid, tampered := filepath.Join(dir, "valid.log"), filepath.Join(dir, "tampered.log")
+
+	out := &syncBuffer{}
+	writeEvents(t, New(out, Params{}), 2)
+	require.NoError(t, os.WriteFile(valid, out.Bytes(), 0o600))
+	require.NoError(t, os.WriteFile(tampered, bytes.Replace(out.Bytes(), []byte("42"), []byte("43"), 1), 0o600))
+
+	var stdout, stderr bytes.Buffer
+	require.Equal(t, ExitOK, VerifyCmd([]string{valid}, nil, &stdout, &stderr))
+	require.Contains(t, stdout.String(), "2 entries verified")
+
+	require.Equal(t, ExitTampered, VerifyCmd([]string{valid, tampered}, nil, &stdout, &stderr))
+	require.Contains(t, stdout.String(), "line 1: audit log is tampered: hash mismatch")
+
+	require.Equal(t, ExitOK, VerifyCmd(nil, bytes.NewReader(out.Bytes()), &stdout, &stderr))
+	require.Equal(t, ExitError, VerifyCmd([]string{filepath.Join(dir, "missing.log")}, nil, &stdout, &stderr))
+
+	keyFile, keyed := filepath.Join(dir, "audit.key"), filepath.Join(dir, "keyed.log")
+	require.NoError(t, os.WriteFile(keyFile, []byte("secret\n"), 0o600))
+	out = &syncBuffer{}
+	writeEvents(t, New(out, Params{Key: []byte("secret")}), 2)
+	require.NoError(t, os.WriteFile(keyed, out.Bytes(), 0o600))
+
+	require.Equal(t, ExitTampered, VerifyCmd([]string{keyed}, nil, &stdout, &stderr))
+	require.Equal(t, ExitOK, VerifyCmd([]string{"-key-file", keyFile, keyed}, nil, &stdout, &stderr))
+	t.Setenv("AUDIT_KEY_FILE", keyFile)
+	require.Equal(t, ExitOK, VerifyCmd([]string{keyed}, nil, &stdout, &stderr))
+	require.Equal(t, ExitError, VerifyCmd([]string{"-key-file", filepath.Join(dir, "missing.key"), keyed}, nil, &stdout, &stderr))
+}
@@ -0,0 +1,14 @@
+// Command auditverify verifies the hash chain of audit log files, see audit.VerifyCmd for the key:
+//
+//	go run github.com/sorohimm/utils/log/audit/cmd/auditverify -key-file /etc/app/audit.key /var/log/app/audit.log
+package main
+
+import (
+	"os"
+
+	"github.com/sorohimm/utils/log/audit"
+)
+
+func main() {
+	os.Exit(audit.VerifyCmd(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
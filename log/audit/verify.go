@@ -0,0 +1,110 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+// maxLine is the longest entry Verify reads.
+const maxLine = 1 << 20
+
+// ErrTampered is wrapped by the errors of Verify about a broken chain.
+var ErrTampered = errors.New("audit log is tampered")
+
+// Exit codes of VerifyCmd.
+const (
+	ExitOK       = 0
+	ExitTampered = 1
+	ExitError    = 2
+)
+
+var hashSuffix = []byte(`,"hash":"`)
+
+// Verify checks the hash chain of the entries written by Logger with the key (see Params.Key)
+// and returns the number of them. A modified, removed, reordered or inserted entry breaks the chain,
+// the error wraps ErrTampered and names the line. Only the end of the log can be cut off unnoticed,
+// so compare the returned number or the last hash with the expected one if it is known.
+func Verify(r io.Reader, key []byte) (int, error) {
+	s := bufio.NewScanner(r)
+	s.Buffer(nil, maxLine)
+
+	n := 0
+	c := chain{key: key}
+	for line := 1; s.Scan(); line++ {
+		if len(s.Bytes()) == 0 {
+			continue
+		}
+		if err := c.next(s.Bytes()); err != nil {
+			return n, fmt.Errorf("line %d: %w", line, err)
+		}
+		n++
+	}
+	if err := s.Err(); err != nil {
+		return n, fmt.Errorf("read audit log error: %w", err)
+	}
+
+	return n, nil
+}
+
+// VerifyCmd verifies the audit log files given as arguments, or the standard input if there are none,
+// and reports the result. The HMAC key is read from the file given by the -key-file flag
+// or the AUDIT_KEY_FILE env variable, a trailing line ending is ignored. It is meant to be called by a tiny main:
+//
+//	func main() {
+//	    os.Exit(audit.VerifyCmd(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+//	}
+func VerifyCmd(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("auditverify", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	keyFile := fs.String("key-file", os.Getenv("AUDIT_KEY_FILE"), "file with the HMAC key of the chain")
+	if err := fs.Parse(args); err != nil {
+		return ExitError
+	}
+
+	var key []byte
+	if *keyFile != "" {
+		b, err := os.ReadFile(*keyFile)
+		if err != nil {
+			_, _ = fmt.Fprintf(stderr, "read key error: %v\n", err)
+			return ExitError
+		}
+		key = bytes.TrimRight(b, "\r\n")
+	}
+
+	verify := func(name string, r io.Reader) int {
+		n, err := Verify(r, key)
+		switch {
+		case errors.Is(err, ErrTampered):
+			_, _ = fmt.Fprintf(stdout, "%s: %v\n", name, err)
+			return ExitTampered
+		case err != nil:
+			_, _ = fmt.Fprintf(stderr, "%s: %v\n", name, err)
+			return ExitError
+		}
+		_, _ = fmt.Fprintf(stdout, "%s: %d entries verified\n", name, n)
+		return ExitOK
+	}
+
+	if fs.NArg() == 0 {
+		return verify("stdin", stdin)
+	}
+
+	code := ExitOK
+	for _, path := range fs.Args() {
+		f, err := os.Open(path)
+		if err != nil {
+			_, _ = fmt.Fprintf(stderr, "%v\n", err)
+			code = max(code, ExitError)
+			continue
+		}
+		code = max(code, verify(path, f))
+		_ = f.Close()
+	}
+
+	return code
+}
@@ -0,0 +1,243 @@
+package log
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Wildcard is the component name of the default level in a levels map.
+const Wildcard = "*"
+
+// NewLevels creates Levels with the default level and the component levels from the map,
+// e.g. {"db.pool": "debug", "http": "warn"}. The Wildcard key sets the default level.
+func NewLevels(def zap.AtomicLevel, m map[string]string) (*Levels, error) {
+	l := &Levels{def: def}
+	l.levels.Store(&componentLevels{})
+
+	for component, level := range m {
+		var lvl zapcore.Level
+		if err := lvl.UnmarshalText([]byte(level)); err != nil {
+			return nil, fmt.Errorf("invalid level of component %s: %w", component, err)
+		}
+		l.Set(component, lvl)
+	}
+
+	return l, nil
+}
+
+// Levels resolves the level of a component from a hierarchical map: the level of "db.pool.conn"
+// is the one of "db.pool.conn", "db.pool" or "db", whichever is set first, or the default level.
+// Levels can be changed at runtime, loggers pick the changes up immediately.
+type Levels struct {
+	def    zap.AtomicLevel
+	mu     sync.Mutex // serializes copy-on-write updates
+	levels atomic.Pointer[componentLevels]
+}
+
+type componentLevels struct {
+	m   map[string]zapcore.Level
+	min zapcore.Level
+}
+
+// Set sets the level of the component, the Wildcard sets the default level.
+func (l *Levels) Set(component string, level zapcore.Level) {
+	if component == Wildcard {
+		l.def.SetLevel(level)
+		return
+	}
+
+	l.update(func(m map[string]zapcore.Level) {
+		m[component] = level
+	})
+}
+
+// Unset removes the level of the component, so it falls back to its parent.
+func (l *Levels) Unset(component string) {
+	l.update(func(m map[string]zapcore.Level) {
+		delete(m, component)
+	})
+}
+
+func (l *Levels) update(fn func(map[string]zapcore.Level)) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	current := l.levels.Load()
+	next := &componentLevels{m: make(map[string]zapcore.Level, len(current.m)+1), min: zapcore.FatalLevel}
+	for k, v := range current.m {
+		next.m[k] = v
+	}
+	fn(next.m)
+	for _, v := range next.m {
+		if v < next.min {
+			next.min = v
+		}
+	}
+
+	l.levels.Store(next)
+}
+
+// Resolve returns the level of the component.
+func (l *Levels) Resolve(component string) zapcore.Level {
+	m := l.levels.Load().m
+	for name := component; name != ""; {
+		if level, ok := m[name]; ok {
+			return level
+		}
+		i := strings.LastIndexByte(name, '.')
+		if i < 0 {
+			break
+		}
+		name = name[:i]
+	}
+
+	return l.def.Level()
+}
+
+// Map returns the component levels including the default one under the Wildcard key.
+func (l *Levels) Map() map[string]string {
+	current := l.levels.Load()
+	res := make(map[string]string, len(current.m)+1)
+	for k, v := range current.m {
+		res[k] = v.String()
+	}
+	res[Wildcard] = l.def.Level().String()
+
+	return res
+}
+
+// Enabled reports whether the level is enabled for any component,
+// it is the enabler of the core the component cores are built on.
+func (l *Levels) Enabled(level zapcore.Level) bool {
+	current := l.levels.Load()
+	if len(current.m) > 0 && level >= current.min {
+		return true
+	}
+
+	return l.def.Enabled(level)
+}
+
+// ServeHTTP reports the levels on GET and changes them on PUT with a map of component levels,
+// an empty level unsets the component:
+//
+//	curl -X PUT localhost:8080/log/levels -d '{"db.pool":"debug","http":""}'
+func (l *Levels) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var m map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&m); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("request body error: %w", err))
+			return
+		}
+
+		levels := make(map[string]zapcore.Level, len(m))
+		components := make([]string, 0, len(m))
+		for component, level := range m {
+			components = append(components, component)
+			if level == "" {
+				continue
+			}
+			var lvl zapcore.Level
+			if err := lvl.UnmarshalText([]byte(level)); err != nil {
+				writeError(w, http.StatusBadRequest, fmt.Errorf("invalid level of component %s: %w", component, err))
+				return
+			}
+			levels[component] = lvl
+		}
+		sort.Strings(components)
+
+		for _, component := range components {
+			if level, ok := levels[component]; ok {
+				l.Set(component, level)
+				continue
+			}
+			l.Unset(component)
+		}
+	default:
+		w.Header().Set("Allow", "GET, PUT")
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("only GET and PUT are supported"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(l.Map())
+}
+
+// componentCore filters entries by the level of its component.
+type componentCore struct {
+	zapcore.Core
+	levels    *Levels
+	component string
+}
+
+// withComponent returns the core filtering by the level of the component.
+// A component core is replaced rather than wrapped, so a child logger may be more verbose
+// than its parent.
+func withComponent(core zapcore.Core, levels *Levels, component string) zapcore.Core {
+	if c, ok := core.(*componentCore); ok {
+		core = c.Core
+	}
+
+	return &componentCore{Core: core, levels: levels, component: component}
+}
+
+func (c *componentCore) Enabled(level zapcore.Level) bool {
+	return level >= c.levels.Resolve(c.component)
+}
+
+func (c *componentCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.Enabled(ent.Level) {
+		return ce
+	}
+
+	return c.Core.Check(ent, ce)
+}
+
+func (c *componentCore) With(fields []zapcore.Field) zapcore.Core {
+	return &componentCore{Core: c.Core.With(fields), levels: c.levels, component: c.component}
+}
+
+// Levels returns the component levels of the logger, nil if the logger has none.
+func (o *Zap) Levels() *Levels {
+	return o.levels
+}
+
+// Component returns a child logger of the component nested into the logger one:
+// a "pool" child of the "db" logger is the "db.pool" component with its own level (see Levels).
+// The name is also added to the logger name the way zap.Logger.Named does.
+func (o *Zap) Component(name string) *Zap {
+	component := name
+	if o.component != "" {
+		component = o.component + "." + name
+	}
+
+	return o.withComponent(func(l *zap.Logger) *zap.Logger {
+		return l.Named(name)
+	}, component)
+}
+
+// withComponent returns the logger derived by fn filtering by the level of the component.
+func (o *Zap) withComponent(fn func(*zap.Logger) *zap.Logger, component string) *Zap {
+	c := o.derive(func(l *zap.Logger) *zap.Logger {
+		l = fn(l)
+		if o.levels == nil {
+			return l
+		}
+
+		return l.WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+			return withComponent(core, o.levels, component)
+		}))
+	})
+	c.component = component
+
+	return c
+}
@@ -0,0 +1,78 @@
+package log
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/sorohimm/utils/cfg/types"
+)
+
+func newFileZap(t *testing.T, c Config) (*Zap, func() []string) {
+	t.Helper()
+	out := filepath.Join(t.TempDir(), "out.log")
+	c.OutputPaths = []string{out}
+
+	logger, err := New(c)
+	require.NoError(t, err)
+
+	return logger, func() []string {
+		require.NoError(t, logger.Sync())
+		data, err := os.ReadFile(out)
+		require.NoError(t, err)
+		return strings.Split(strings.TrimSpace(string(data)), "\n")
+	}
+}
+
+func TestComponentLevels(t *testing.T) {
+	logger, lines := newFileZap(t, Config{
+		Level:  types.LogLevel(zapcore.InfoLevel),
+		Levels: map[string]string{"db": "debug", "http": "warn"},
+	})
+
+	logger.Debug("root debug")
+	logger.WithSource("db.pool").Debug("pool debug")
+	logger.WithSource("http").Info("http info")
+	logger.WithSource("http").Warn("http warn")
+	logger.WithSource("db").WithSource("http").Info("http info again")
+	logger.Component("db").Component("pool").Debug("component pool debug")
+	logger.Named("db").Debug("named debug")
+
+	logger.Levels().Set("http", zapcore.DebugLevel)
+	logger.WithSource("http").Debug("http debug")
+	logger.Levels().Unset("db")
+	logger.WithSource("db").Debug("db debug")
+
+	got := lines()
+	require.Len(t, got, 4)
+	require.Contains(t, got[0], `"msg":"pool debug","source":"db.pool"`)
+	require.Contains(t, got[1], `"msg":"http warn","source":"http"`)
+	require.Contains(t, got[2], `"logger":"db.pool"`)
+	require.Contains(t, got[2], `"msg":"component pool debug"`)
+	require.Contains(t, got[3], `"msg":"http debug"`)
+}
+
+func TestLevelsHandler(t *testing.T) {
+	logger, err := New(Config{Levels: map[string]string{"db": "debug"}})
+	require.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	logger.Levels().ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/",
+		strings.NewReader(`{"db":"","http":"error","*":"warn"}`)))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.JSONEq(t, `{"http":"error","*":"warn"}`, rec.Body.String())
+	require.Equal(t, zapcore.WarnLevel, logger.Levels().Resolve("db.pool"))
+
+	rec = httptest.NewRecorder()
+	logger.Levels().ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/", strings.NewReader(`{"db":"loud"}`)))
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+
+	_, err = New(Config{Levels: map[string]string{"db": "loud"}})
+	require.ErrorContains(t, err, "invalid level of component db")
+}
@@ -0,0 +1,309 @@
+package log
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/sorohimm/utils/cfg/types"
+)
+
+// Time formats of Config.TimeFormat, any other value is used as a Go time layout.
+const (
+	TimeISO8601     = "iso8601"
+	TimeRFC3339     = "rfc3339"
+	TimeRFC3339Nano = "rfc3339nano"
+	TimeEpoch       = "epoch"
+	TimeEpochMillis = "epochMillis"
+	TimeEpochNanos  = "epochNanos"
+)
+
+// Config describes a Zap logger. It is meant to be a part of a service config loaded with cfg.Load:
+//
+//	type Cfg struct {
+//	    Log log.Config `yaml:"log" envPrefix:"LOG_"`
+//	}
+//
+// Empty fields fall back to the values used by NewZap.
+type Config struct {
+	Level             types.LogLevel    `yaml:"level" env:"LEVEL"`
+	Encoding          string            `yaml:"encoding" env:"ENCODING" validate:"omitempty,oneof=json console"`
+	OutputPaths       []string          `yaml:"outputPaths" env:"OUTPUT_PATHS"`
+	Rotation          RotationConfig    `yaml:"rotation" envPrefix:"ROTATION_"`
+	Async             AsyncConfig       `yaml:"async" envPrefix:"ASYNC_"`
+	ErrorOutputPaths  []string          `yaml:"errorOutputPaths" env:"ERROR_OUTPUT_PATHS"`
+	Keys              KeysConfig        `yaml:"keys" envPrefix:"KEY_"`
+	TimeFormat        string            `yaml:"timeFormat" env:"TIME_FORMAT"`
+	Sampling          SamplingConfig    `yaml:"sampling" envPrefix:"SAMPLING_"`
+	Dedupe            DedupeConfig      `yaml:"dedupe" envPrefix:"DEDUPE_"`
+	Redact            RedactConfig      `yaml:"redact" envPrefix:"REDACT_"`
+	DisableCaller     bool              `yaml:"disableCaller" env:"DISABLE_CALLER"`
+	DisableStacktrace bool              `yaml:"disableStacktrace" env:"DISABLE_STACKTRACE"`
+	InitialFields     map[string]string `yaml:"initialFields" env:"INITIAL_FIELDS"`
+	// Levels are component levels, e.g. {"db.pool": "debug", "http": "warn"} (see Levels)
+	Levels map[string]string `yaml:"levels" env:"LEVELS"`
+}
+
+// KeysConfig holds the keys of the fields every entry has. Empty keys fall back to the defaults,
+// except Function: the caller function is logged only when its key is set.
+type KeysConfig struct {
+	Message    string `yaml:"message" env:"MESSAGE"`
+	Level      string `yaml:"level" env:"LEVEL"`
+	Time       string `yaml:"time" env:"TIME"`
+	Name       string `yaml:"name" env:"NAME"`
+	Caller     string `yaml:"caller" env:"CALLER"`
+	Function   string `yaml:"function" env:"FUNCTION"`
+	Stacktrace string `yaml:"stacktrace" env:"STACKTRACE"`
+}
+
+// SamplingConfig enables zap sampling: within each Tick the first Initial entries
+// with the same level and message are logged and then every Thereafter-th one.
+// Sampling is disabled when Initial is zero.
+type SamplingConfig struct {
+	Initial    int           `yaml:"initial" env:"INITIAL" validate:"min=0"`
+	Thereafter int           `yaml:"thereafter" env:"THEREAFTER" validate:"min=0"`
+	Tick       time.Duration `yaml:"tick" env:"TICK"`
+}
+
+// DefaultConfig returns the config NewZap builds loggers with.
+func DefaultConfig() Config {
+	return Config{
+		Encoding:         "json",
+		OutputPaths:      []string{"stderr"},
+		ErrorOutputPaths: []string{"stderr"},
+		Keys: KeysConfig{
+			Message:    "msg",
+			Level:      "level",
+			Time:       "ts",
+			Name:       "logger",
+			Caller:     "c",
+			Stacktrace: "s",
+		},
+		TimeFormat: TimeISO8601,
+		Sampling: SamplingConfig{
+			Initial:    100,
+			Thereafter: 100,
+			Tick:       time.Second,
+		},
+	}
+}
+
+func (c Config) withDefaults() Config {
+	d := DefaultConfig()
+
+	if c.Encoding == "" {
+		c.Encoding = d.Encoding
+	}
+	if len(c.OutputPaths) == 0 {
+		c.OutputPaths = d.OutputPaths
+	}
+	if len(c.ErrorOutputPaths) == 0 {
+		c.ErrorOutputPaths = d.ErrorOutputPaths
+	}
+	if c.TimeFormat == "" {
+		c.TimeFormat = d.TimeFormat
+	}
+	if c.Sampling.Tick == 0 {
+		c.Sampling.Tick = d.Sampling.Tick
+	}
+
+	keys := []struct{ dst, def *string }{
+		{&c.Keys.Message, &d.Keys.Message},
+		{&c.Keys.Level, &d.Keys.Level},
+		{&c.Keys.Time, &d.Keys.Time},
+		{&c.Keys.Name, &d.Keys.Name},
+		{&c.Keys.Caller, &d.Keys.Caller},
+		{&c.Keys.Stacktrace, &d.Keys.Stacktrace},
+	}
+	for _, k := range keys {
+		if *k.dst == "" {
+			*k.dst = *k.def
+		}
+	}
+
+	return c
+}
+
+// New creates a new instance of zap logger described by the config.
+// Returns: new instance of zap logger and error if any
+func New(c Config) (*Zap, error) {
+	c = c.withDefaults()
+
+	level := zap.NewAtomicLevelAt(c.Level.Level())
+
+	enc, err := newEncoder(c)
+	if err != nil {
+		return nil, err
+	}
+
+	levels, err := NewLevels(level, c.Levels)
+	if err != nil {
+		return nil, err
+	}
+
+	var redact *redactor
+	if c.Redact.Enabled {
+		if redact, err = newRedactor(c.Redact); err != nil {
+			return nil, err
+		}
+	}
+
+	// the outputs are opened last, so no error return leaks them
+	sink, files, closeSink, err := openOutputs(c)
+	if err != nil {
+		return nil, err
+	}
+	errSink, _, err := zap.Open(c.ErrorOutputPaths...)
+	if err != nil {
+		closeSink()
+		return nil, fmt.Errorf("open error outputs error: %w", err)
+	}
+
+	var (
+		core  zapcore.Core
+		async *AsyncWriter
+	)
+	if c.Async.Enabled {
+		async = NewAsyncWriter(sink, c.Async)
+		core = newAsyncCore(enc, async, levels)
+	} else {
+		core = zapcore.NewCore(enc, sink, levels)
+	}
+	if redact != nil {
+		core = &redactCore{Core: core, r: redact}
+	}
+	if c.Sampling.Initial > 0 {
+		core = zapcore.NewSamplerWithOptions(core, c.Sampling.Tick, c.Sampling.Initial, c.Sampling.Thereafter)
+	}
+	if c.Dedupe.Window > 0 {
+		core = newDedupeCore(core, c.Dedupe)
+	}
+
+	opts := []zap.Option{zap.ErrorOutput(errSink)}
+	if !c.DisableCaller {
+		opts = append(opts, zap.AddCaller())
+	}
+	if !c.DisableStacktrace {
+		opts = append(opts, zap.AddStacktrace(zapcore.ErrorLevel))
+	}
+	if len(c.InitialFields) > 0 {
+		opts = append(opts, zap.Fields(initialFields(c.InitialFields)...))
+	}
+
+	core = withComponent(core, levels, "")
+
+	return &Zap{Logger: zap.New(core, opts...), level: level, levels: levels, files: files, async: async, redact: redact}, nil
+}
+
+// openOutputs opens the output paths, the files are opened as RotatingFile so they can be reopened
+// and rotated if the rotation is enabled.
+func openOutputs(c Config) (zapcore.WriteSyncer, []*RotatingFile, func(), error) {
+	var (
+		files []*RotatingFile
+		paths []string
+	)
+	closeAll := func() {
+		for _, f := range files {
+			_ = f.Close()
+		}
+	}
+	for _, p := range c.OutputPaths {
+		if !isFile(p) {
+			paths = append(paths, p)
+			continue
+		}
+		f, err := NewRotatingFile(strings.TrimPrefix(p, "file://"), c.Rotation)
+		if err != nil {
+			closeAll()
+			return nil, nil, nil, fmt.Errorf("open outputs error: %w", err)
+		}
+		files = append(files, f)
+	}
+
+	syncers := make([]zapcore.WriteSyncer, 0, len(files)+1)
+	for _, f := range files {
+		syncers = append(syncers, f)
+	}
+	closeSink := closeAll
+	if len(paths) > 0 {
+		sink, closeOthers, err := zap.Open(paths...)
+		if err != nil {
+			closeAll()
+			return nil, nil, nil, fmt.Errorf("open outputs error: %w", err)
+		}
+		syncers = append(syncers, sink)
+		closeSink = func() {
+			closeAll()
+			closeOthers()
+		}
+	}
+
+	return zapcore.NewMultiWriteSyncer(syncers...), files, closeSink, nil
+}
+
+func newEncoder(c Config) (zapcore.Encoder, error) {
+	encCfg := zapcore.EncoderConfig{
+		MessageKey:     c.Keys.Message,
+		LevelKey:       c.Keys.Level,
+		TimeKey:        c.Keys.Time,
+		NameKey:        c.Keys.Name,
+		CallerKey:      c.Keys.Caller,
+		FunctionKey:    c.Keys.Function,
+		StacktraceKey:  c.Keys.Stacktrace,
+		LineEnding:     zapcore.DefaultLineEnding,
+		EncodeLevel:    zapcore.CapitalLevelEncoder,
+		EncodeTime:     timeEncoder(c.TimeFormat),
+		EncodeDuration: zapcore.StringDurationEncoder,
+		EncodeCaller:   zapcore.ShortCallerEncoder,
+	}
+	if encCfg.FunctionKey == "" {
+		encCfg.FunctionKey = zapcore.OmitKey
+	}
+
+	switch c.Encoding {
+	case "json":
+		return zapcore.NewJSONEncoder(encCfg), nil
+	case "console":
+		return zapcore.NewConsoleEncoder(encCfg), nil
+	}
+
+	return nil, fmt.Errorf("no encoder registered for name %q", c.Encoding)
+}
+
+func timeEncoder(format string) zapcore.TimeEncoder {
+	switch format {
+	case TimeISO8601:
+		return zapcore.ISO8601TimeEncoder
+	case TimeRFC3339:
+		return zapcore.RFC3339TimeEncoder
+	case TimeRFC3339Nano:
+		return zapcore.RFC3339NanoTimeEncoder
+	case TimeEpoch:
+		return zapcore.EpochTimeEncoder
+	case TimeEpochMillis:
+		return zapcore.EpochMillisTimeEncoder
+	case TimeEpochNanos:
+		return zapcore.EpochNanosTimeEncoder
+	}
+
+	return zapcore.TimeEncoderOfLayout(format)
+}
+
+func initialFields(m map[string]string) []zap.Field {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	fields := make([]zap.Field, 0, len(keys))
+	for _, k := range keys {
+		fields = append(fields, zap.String(strings.TrimSpace(k), m[k]))
+	}
+
+	return fields
+}
@@ -0,0 +1,74 @@
+package log
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/sorohimm/utils/cfg/cfgtest"
+)
+
+type serviceCfg struct {
+	Log Config `yaml:"log" envPrefix:"LOG_"`
+}
+
+func TestNew(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "out.log")
+
+	c := serviceCfg{}
+	cfgtest.Load(t, &c, `
+		log:
+		  level: info
+		  outputPaths: [`+out+`]
+		  keys:
+		    message: message
+		    time: time
+		  timeFormat: "2006"
+		  disableCaller: true
+		  initialFields:
+		    app: test
+	`, map[string]string{"LOG_LEVEL": "warn", "LOG_KEY_LEVEL": "severity"})
+
+	logger, err := New(c.Log)
+	require.NoError(t, err)
+	logger.Info("skipped")
+	logger.Warn("logged", zap.Int("n", 1))
+	require.NoError(t, logger.Sync())
+
+	data, err := os.ReadFile(out)
+	require.NoError(t, err)
+
+	var entry map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &entry))
+	require.Len(t, entry, 5)
+	require.Equal(t, "logged", entry["message"])
+	require.Equal(t, "WARN", entry["severity"])
+	require.Len(t, entry["time"], 4)
+	require.Equal(t, "test", entry["app"])
+	require.Equal(t, float64(1), entry["n"])
+}
+
+func TestConfigValidation(t *testing.T) {
+	c := serviceCfg{}
+	err := cfgtest.LoadError(t, &c, "log:\n  level: verbose\n", nil)
+	require.ErrorContains(t, err, `line 2: invalid log level "verbose"`)
+
+	err = cfgtest.LoadError(t, &c, "log:\n  encoding: xml\n", nil)
+	cfgtest.AssertInvalid(t, err, "Encoding", "oneof")
+}
+
+func TestNewInvalidOpensNothing(t *testing.T) {
+	out, errOut := filepath.Join(t.TempDir(), "out.log"), filepath.Join(t.TempDir(), "err.log")
+	_, err := New(Config{
+		OutputPaths:      []string{out},
+		ErrorOutputPaths: []string{errOut},
+		Redact:           RedactConfig{Enabled: true, Patterns: []string{"("}},
+	})
+	require.ErrorContains(t, err, "invalid redact pattern")
+	require.NoFileExists(t, out)
+	require.NoFileExists(t, errOut)
+}
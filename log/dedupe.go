@@ -0,0 +1,234 @@
+package log
+
+import (
+	"fmt"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// DedupeConfig suppresses repeated entries: within Window only the first entry with the same level,
+// message and values of Fields (of all the fields if Fields is empty) is logged, the number of the suppressed ones is logged
+// once the window ends as "<message> (repeated N times)" with the repeated field.
+// Unlike sampling it is keyed by the content and reports what it suppressed.
+// Deduplication is disabled when Window is zero.
+type DedupeConfig struct {
+	Window time.Duration `yaml:"window" env:"WINDOW"`
+	// Fields are the keys of the fields that are a part of the dedupe key, e.g. "error".
+	// All the fields are if empty, so entries sharing a message but not the values are not collapsed
+	Fields []string `yaml:"fields" env:"FIELDS"`
+}
+
+// dedupeCore logs the first entry of a key within the window and counts the rest.
+type dedupeCore struct {
+	zapcore.Core
+	state  *dedupeState
+	fields []zapcore.Field
+}
+
+type dedupeState struct {
+	window time.Duration
+	keys   []string
+
+	mu      sync.Mutex
+	entries map[string]*dedupeEntry
+}
+
+type dedupeEntry struct {
+	suppressed int64
+}
+
+func newDedupeCore(core zapcore.Core, c DedupeConfig) zapcore.Core {
+	return &dedupeCore{Core: core, state: &dedupeState{
+		window:  c.Window,
+		keys:    c.Fields,
+		entries: make(map[string]*dedupeEntry),
+	}}
+}
+
+func (c *dedupeCore) With(fields []zapcore.Field) zapcore.Core {
+	return &dedupeCore{
+		Core:   c.Core.With(fields),
+		state:  c.state,
+		fields: append(c.fields[:len(c.fields):len(c.fields)], fields...),
+	}
+}
+
+func (c *dedupeCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+
+	return ce
+}
+
+// Write logs the entry through the wrapped core unless it is a repetition.
+func (c *dedupeCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	key := c.key(ent, fields)
+
+	c.state.mu.Lock()
+	if e, ok := c.state.entries[key]; ok {
+		e.suppressed++
+		c.state.mu.Unlock()
+		return nil
+	}
+	e := &dedupeEntry{}
+	c.state.entries[key] = e
+	c.state.mu.Unlock()
+
+	time.AfterFunc(c.state.window, func() {
+		c.state.mu.Lock()
+		delete(c.state.entries, key)
+		n := e.suppressed
+		c.state.mu.Unlock()
+
+		if n > 0 {
+			c.summary(ent, n)
+		}
+	})
+
+	if ce := c.Core.Check(ent, nil); ce != nil {
+		ce.Write(fields...)
+	}
+
+	return nil
+}
+
+func (c *dedupeCore) summary(ent zapcore.Entry, n int64) {
+	ent.Message = fmt.Sprintf("%s (repeated %d times)", ent.Message, n)
+	ent.Time = time.Now()
+	ent.Caller = zapcore.EntryCaller{}
+	ent.Stack = ""
+
+	if ce := c.Core.Check(ent, nil); ce != nil {
+		ce.Write(zap.Int64("repeated", n))
+	}
+}
+
+// key returns the dedupe key of the entry: its level, message and the values of the key fields,
+// or of all the fields in the key order if no key fields are configured.
+func (c *dedupeCore) key(ent zapcore.Entry, fields []zapcore.Field) string {
+	var b strings.Builder
+	b.WriteString(ent.Level.String())
+	b.WriteByte(0)
+	b.WriteString(ent.Message)
+
+	enc := zapcore.NewMapObjectEncoder()
+	for _, f := range c.fields {
+		f.AddTo(enc)
+	}
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+
+	keys := c.state.keys
+	if len(keys) == 0 {
+		keys = make([]string, 0, len(enc.Fields))
+		for k := range enc.Fields {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+	}
+	for _, k := range keys {
+		b.WriteByte(0)
+		b.WriteString(k)
+		b.WriteByte('=')
+		if v, ok := enc.Fields[k]; ok {
+			_, _ = fmt.Fprint(&b, v)
+		}
+	}
+
+	return b.String()
+}
+
+// callSite is the rate limit state of an Every or FirstN call site.
+type callSite struct {
+	mu         sync.Mutex
+	last       time.Time
+	count      int
+	suppressed int
+}
+
+type callSiteKey struct {
+	pc    uintptr
+	every time.Duration
+	first int
+}
+
+// callSites holds the state of every Every and FirstN call site and argument for the process lifetime.
+// It is never evicted, as the call sites are bounded by the code, so the argument must be a constant
+// rather than a value computed per call, e.g. Every(time.Duration(n) * time.Second) with a varying n.
+var callSites sync.Map
+
+func site(key callSiteKey) *callSite {
+	s, _ := callSites.LoadOrStore(key, &callSite{})
+
+	return s.(*callSite)
+}
+
+func callerPC() uintptr {
+	var pcs [1]uintptr
+	runtime.Callers(3, pcs[:])
+
+	return pcs[0]
+}
+
+// Every returns the logger if the call site did not log within the duration and a logger
+// discarding everything otherwise, so a call site logs at most once per the duration:
+//
+//	logger.Every(time.Minute).Warn("cache is unavailable")
+//
+// The logged entry has the suppressed field with the number of the entries discarded since the previous one.
+// The state is kept per call site and duration forever, so d must not vary between calls (see callSites).
+func (o *Zap) Every(d time.Duration) *Zap {
+	s := site(callSiteKey{pc: callerPC(), every: d})
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if !s.last.IsZero() && now.Sub(s.last) < d {
+		s.suppressed++
+		return o.derive(nop)
+	}
+	s.last = now
+
+	return o.withSuppressed(s)
+}
+
+// FirstN returns the logger for the first n calls of the call site and a logger discarding
+// everything afterwards. The count is kept per call site and n forever, so n must not vary between calls.
+func (o *Zap) FirstN(n int) *Zap {
+	s := site(callSiteKey{pc: callerPC(), first: n})
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.count >= n {
+		return o.derive(nop)
+	}
+	s.count++
+
+	return o
+}
+
+func (o *Zap) withSuppressed(s *callSite) *Zap {
+	if s.suppressed == 0 {
+		return o
+	}
+	n := s.suppressed
+	s.suppressed = 0
+
+	return o.derive(func(l *zap.Logger) *zap.Logger {
+		return l.With(zap.Int("suppressed", n))
+	})
+}
+
+func nop(*zap.Logger) *zap.Logger {
+	return zap.NewNop()
+}
@@ -0,0 +1,88 @@
+package log
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestDedupe(t *testing.T) {
+	logger, lines := newFileZap(t, Config{
+		DisableCaller: true,
+		Dedupe:        DedupeConfig{Window: 100 * time.Millisecond, Fields: []string{"error"}},
+	})
+
+	db := logger.WithSource("db")
+	for i := 0; i < 5; i++ {
+		db.Error("query failed", zap.Error(errors.New("connection refused")), zap.Int("attempt", i))
+	}
+	db.Error("query failed", zap.Error(errors.New("timeout")))
+	logger.Info("other")
+
+	got := lines()
+	require.Len(t, got, 3)
+	require.Contains(t, got[0], `"attempt":0`)
+	require.Contains(t, got[1], `"error":"timeout"`)
+	require.Contains(t, got[2], `"msg":"other"`)
+
+	require.Eventually(t, func() bool { return len(lines()) == 4 }, time.Second, 10*time.Millisecond)
+	got = lines()
+	require.Contains(t, got[3], `"msg":"query failed (repeated 4 times)","source":"db","repeated":4`)
+
+	// a new window starts after the summary
+	db.Error("query failed", zap.Error(errors.New("connection refused")))
+	require.Len(t, lines(), 5)
+}
+
+func TestDedupeAllFields(t *testing.T) {
+	logger, lines := newFileZap(t, Config{DisableCaller: true, Dedupe: DedupeConfig{Window: time.Hour}})
+
+	req := logger.With(zap.String("method", "GET"))
+	req.Info("request", zap.String("path", "/a"))
+	req.Info("request", zap.String("path", "/b"))
+	req.Info("request", zap.String("path", "/a"))
+	logger.With(zap.String("method", "POST")).Info("request", zap.String("path", "/a"))
+	logger.Error("failed", zap.Error(errors.New("timeout")))
+	logger.Error("failed", zap.Error(errors.New("refused")))
+
+	got := lines()
+	require.Len(t, got, 5)
+	require.Contains(t, got[0], `"path":"/a"`)
+	require.Contains(t, got[1], `"path":"/b"`)
+	require.Contains(t, got[2], `"method":"POST"`)
+	require.Contains(t, got[3], `"error":"timeout"`)
+	require.Contains(t, got[4], `"error":"refused"`)
+}
+
+func TestEvery(t *testing.T) {
+	logger, lines := newFileZap(t, Config{DisableCaller: true})
+
+	for i := 0; i < 3; i++ {
+		logger.Every(50*time.Millisecond).Info("every", zap.Int("i", i))
+	}
+	time.Sleep(60 * time.Millisecond)
+	logger.Every(50 * time.Millisecond).Info("another call site")
+	for i := 0; i < 5; i++ {
+		logger.FirstN(2).Info("first", zap.Int("i", i))
+	}
+	for i := 0; i < 2; i++ {
+		if i == 1 {
+			time.Sleep(60 * time.Millisecond)
+		}
+		for j := 0; j < 3; j++ {
+			logger.Every(50*time.Millisecond).Info("loop", zap.Int("i", i))
+		}
+	}
+
+	got := lines()
+	require.Len(t, got, 6)
+	require.Contains(t, got[0], `"msg":"every","i":0`)
+	require.Contains(t, got[1], `"msg":"another call site"`)
+	require.Contains(t, got[2], `"msg":"first","i":0`)
+	require.Contains(t, got[3], `"msg":"first","i":1`)
+	require.Contains(t, got[4], `"msg":"loop","i":0}`)
+	require.Contains(t, got[5], `"msg":"loop","suppressed":2,"i":1}`)
+}
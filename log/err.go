@@ -0,0 +1,195 @@
+package log
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+
+	pkgerrors "github.com/pkg/errors"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Err returns the "error" field encoding the error structure rather than only its message:
+//
+//	{"msg":"...","chain":[{"msg":"...","type":"..."}],"errors":[...],"fields":{...},"stack":"..."}
+//
+// chain lists the errors reached by Unwrap, errors lists the children of a multi-error
+// such as the ones of errors.Join or cfg.Error (each encoded the same way), fields holds
+// the fields attached to the errors of the chain (see ErrorWithFields), the outer ones winning
+// over the inner ones of the same key, and stack is the innermost github.com/pkg/errors stack trace.
+// A nil pointer error panicking in Error is encoded as "<nil>" the way zap.Error does it.
+func Err(err error) zap.Field {
+	return NamedErr("error", err)
+}
+
+// NamedErr is Err with the key.
+func NamedErr(key string, err error) zap.Field {
+	if err == nil {
+		return zap.Skip()
+	}
+
+	return zap.Object(key, errObject{err})
+}
+
+// ErrorWithFields returns the error with the fields attached, Err logs them.
+func ErrorWithFields(err error, fields ...zap.Field) error {
+	if err == nil {
+		return nil
+	}
+
+	return &fieldsError{err: err, fields: fields}
+}
+
+type fieldsError struct {
+	err    error
+	fields []zap.Field
+}
+
+func (e *fieldsError) Error() string {
+	return e.err.Error()
+}
+
+func (e *fieldsError) Unwrap() error {
+	return e.err
+}
+
+// LogFields returns the attached fields.
+func (e *fieldsError) LogFields() []zap.Field {
+	return e.fields
+}
+
+type stackTracer interface {
+	StackTrace() pkgerrors.StackTrace
+}
+
+type errObject struct {
+	err error
+}
+
+func (o errObject) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("msg", errorMessage(o.err))
+
+	var (
+		chain    errChain
+		fields   []zap.Field
+		keys     = map[string]bool{}
+		stack    pkgerrors.StackTrace
+		children []error
+	)
+	for err := o.err; err != nil; {
+		switch _, ok := err.(*fieldsError); {
+		case ok:
+		case len(chain) > 0 && errorMessage(chain[len(chain)-1]) == errorMessage(err):
+			// a wrapper not changing the message such as the pkg/errors stack one
+			chain[len(chain)-1] = err
+		default:
+			chain = append(chain, err)
+		}
+		if isNilPtr(err) {
+			// its methods may panic as Error does, so the chain ends here
+			break
+		}
+		if fe, ok := err.(interface{ LogFields() []zap.Field }); ok {
+			for _, f := range fe.LogFields() {
+				if f.Key != "" && keys[f.Key] {
+					continue
+				}
+				keys[f.Key] = true
+				fields = append(fields, f)
+			}
+		}
+		if st, ok := err.(stackTracer); ok {
+			stack = st.StackTrace()
+		}
+
+		if multi, ok := err.(interface{ Unwrap() []error }); ok {
+			children = multi.Unwrap()
+			break
+		}
+		err = errors.Unwrap(err)
+	}
+
+	if len(chain) > 1 {
+		if err := enc.AddArray("chain", chain); err != nil {
+			return err
+		}
+	}
+	if len(children) > 0 {
+		if err := enc.AddArray("errors", errArray(children)); err != nil {
+			return err
+		}
+	}
+	if len(fields) > 0 {
+		if err := enc.AddObject("fields", fieldsObject(fields)); err != nil {
+			return err
+		}
+	}
+	if stack != nil {
+		enc.AddString("stack", fmt.Sprintf("%+v", stack))
+	}
+
+	return nil
+}
+
+type errChain []error
+
+func (c errChain) MarshalLogArray(enc zapcore.ArrayEncoder) error {
+	for _, err := range c {
+		err := err
+		if e := enc.AppendObject(zapcore.ObjectMarshalerFunc(func(enc zapcore.ObjectEncoder) error {
+			enc.AddString("msg", errorMessage(err))
+			enc.AddString("type", fmt.Sprintf("%T", err))
+			return nil
+		})); e != nil {
+			return e
+		}
+	}
+
+	return nil
+}
+
+// errorMessage returns the message of the error, "<nil>" if it is a nil pointer panicking in Error.
+func errorMessage(err error) (msg string) {
+	defer func() {
+		if p := recover(); p != nil {
+			if !isNilPtr(err) {
+				panic(p)
+			}
+			msg = "<nil>"
+		}
+	}()
+
+	return err.Error()
+}
+
+func isNilPtr(err error) bool {
+	v := reflect.ValueOf(err)
+
+	return v.Kind() == reflect.Ptr && v.IsNil()
+}
+
+type errArray []error
+
+func (a errArray) MarshalLogArray(enc zapcore.ArrayEncoder) error {
+	for _, err := range a {
+		if err == nil {
+			continue
+		}
+		if e := enc.AppendObject(errObject{err}); e != nil {
+			return e
+		}
+	}
+
+	return nil
+}
+
+type fieldsObject []zap.Field
+
+func (f fieldsObject) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	for _, field := range f {
+		field.AddTo(enc)
+	}
+
+	return nil
+}
@@ -0,0 +1,127 @@
+package log
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	pkgerrors "github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/sorohimm/utils/cfg"
+	"github.com/sorohimm/utils/cfg/cfgtest"
+)
+
+func logErr(t *testing.T, err error) map[string]interface{} {
+	t.Helper()
+
+	logger, lines := newFileZap(t, Config{DisableCaller: true, DisableStacktrace: true})
+	logger.Error("failed", Err(err))
+
+	got := lines()
+	require.Len(t, got, 1)
+
+	var entry map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(got[0]), &entry))
+	if entry["error"] == nil {
+		return nil
+	}
+
+	return entry["error"].(map[string]interface{})
+}
+
+func TestErrChain(t *testing.T) {
+	base := pkgerrors.New("connection refused")
+	err := fmt.Errorf("load user: %w", ErrorWithFields(pkgerrors.Wrap(base, "query"), zap.String("table", "users")))
+
+	got := logErr(t, err)
+	require.Equal(t, "load user: query: connection refused", got["msg"])
+	require.Equal(t, []interface{}{
+		map[string]interface{}{"msg": "load user: query: connection refused", "type": "*fmt.wrapError"},
+		map[string]interface{}{"msg": "query: connection refused", "type": "*errors.withMessage"},
+		map[string]interface{}{"msg": "connection refused", "type": "*errors.fundamental"},
+	}, got["chain"])
+	require.Equal(t, map[string]interface{}{"table": "users"}, got["fields"])
+	require.Contains(t, got["stack"], "TestErrChain")
+}
+
+func TestErrJoin(t *testing.T) {
+	got := logErr(t, fmt.Errorf("save: %w", errors.Join(errors.New("disk full"), fmt.Errorf("close: %w", errors.New("bad fd")))))
+
+	require.Len(t, got["chain"], 2)
+	children := got["errors"].([]interface{})
+	require.Len(t, children, 2)
+	require.Equal(t, map[string]interface{}{"msg": "disk full"}, children[0])
+	require.Equal(t, "close: bad fd", children[1].(map[string]interface{})["msg"])
+	require.Len(t, children[1].(map[string]interface{})["chain"], 2)
+}
+
+func TestErrConfig(t *testing.T) {
+	var c struct {
+		Port int    `yaml:"port" validate:"min=1"`
+		Host string `yaml:"host" validate:"required"`
+	}
+	err := cfgtest.LoadError(t, &c, "port: 0\n", nil)
+	var cfgErr cfg.Error
+	require.ErrorAs(t, err, &cfgErr)
+
+	got := logErr(t, cfgErr)
+	require.Len(t, got["errors"], 2)
+	require.Nil(t, logErr(t, nil))
+}
+
+type valueError struct {
+	msg string
+}
+
+func (e valueError) Error() string {
+	return e.msg
+}
+
+func TestErrNilPointer(t *testing.T) {
+	var nilErr *valueError
+
+	got := logErr(t, nilErr)
+	require.Equal(t, map[string]interface{}{"msg": "<nil>"}, got)
+
+	got = logErr(t, fmt.Errorf("load: %w", nilErr))
+	require.Equal(t, "load: <nil>", got["msg"])
+	require.Equal(t, []interface{}{
+		map[string]interface{}{"msg": "load: <nil>", "type": "*fmt.wrapError"},
+		map[string]interface{}{"msg": "<nil>", "type": "*log.valueError"},
+	}, got["chain"])
+}
+
+func TestErrFieldsOverride(t *testing.T) {
+	inner := ErrorWithFields(errors.New("refused"), zap.String("table", "users"), zap.Int("attempt", 1))
+	err := ErrorWithFields(fmt.Errorf("query: %w", inner), zap.Int("attempt", 3))
+
+	got := logErr(t, err)
+	require.Equal(t, map[string]interface{}{"attempt": float64(3), "table": "users"}, got["fields"])
+}
+
+func TestErrRedact(t *testing.T) {
+	logger, lines := newFileZap(t, Config{
+		DisableCaller: true,
+		Redact:        RedactConfig{Enabled: true, Patterns: []string{`TestErrRedact`}},
+	})
+
+	err := pkgerrors.Wrap(ErrorWithFields(errors.New("dial postgres://app:hunter2@db"),
+		zap.String("password", "hunter2"), zap.String("dsn", "postgres://app:hunter2@db")), "connect")
+	logger.Error("failed", Err(err))
+
+	got := lines()
+	require.Len(t, got, 1)
+	require.NotContains(t, got[0], "hunter2")
+	require.NotContains(t, got[0], "TestErrRedact")
+
+	var entry struct {
+		Error map[string]interface{} `json:"error"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(got[0]), &entry))
+	require.Equal(t, "connect: dial postgres://app:***@db", entry.Error["msg"])
+	require.Equal(t, map[string]interface{}{"password": "***", "dsn": "postgres://app:***@db"}, entry.Error["fields"])
+	require.Contains(t, entry.Error["stack"], "***")
+}
@@ -0,0 +1,164 @@
+package log
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// fieldsKey is the key of the context fields
+type fieldsKey struct{}
+
+// WithFields returns a context carrying the fields in addition to the ones of ctx,
+// FromContext adds them to the context logger. A field replaces the parent field with the same key.
+//
+//	ctx = log.WithFields(ctx, zap.String("order", id))
+//	log.FromContext(ctx).Info("order created") // {"msg":"order created","order":"..."}
+func WithFields(ctx context.Context, fields ...zap.Field) context.Context {
+	if len(fields) == 0 {
+		return ctx
+	}
+
+	return context.WithValue(ctx, fieldsKey{}, mergeFields(ContextFields(ctx), fields))
+}
+
+// mergeFields returns the parent fields not replaced by the fields followed by the fields.
+func mergeFields(parent, fields []zap.Field) []zap.Field {
+	res := make([]zap.Field, 0, len(parent)+len(fields))
+	for _, f := range parent {
+		if !hasKey(fields, f.Key) {
+			res = append(res, f)
+		}
+	}
+	for i, f := range fields {
+		// the last field wins if the key is repeated
+		if !hasKey(fields[i+1:], f.Key) {
+			res = append(res, f)
+		}
+	}
+
+	return res
+}
+
+func hasKey(fields []zap.Field, key string) bool {
+	for _, f := range fields {
+		if f.Key == key {
+			return true
+		}
+	}
+
+	return false
+}
+
+// sameFields reports whether the fields are equal regardless of their order.
+func sameFields(a, b []zap.Field) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for _, f := range a {
+		if !hasField(b, f) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func hasField(fields []zap.Field, field zap.Field) bool {
+	for _, f := range fields {
+		if f.Equals(field) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ContextFields returns the fields of the context, nil if it has none.
+func ContextFields(ctx context.Context) []zap.Field {
+	fields, _ := ctx.Value(fieldsKey{}).([]zap.Field)
+
+	return fields
+}
+
+// WithUserId returns a context with the UserId field.
+func WithUserId(ctx context.Context, id string) context.Context {
+	return WithFields(ctx, zap.String(UserId, id))
+}
+
+// UserIdFromContext returns the UserId field of the context, an empty string if it has none.
+func UserIdFromContext(ctx context.Context) string {
+	for _, f := range ContextFields(ctx) {
+		if f.Key == UserId && f.Type == zapcore.StringType {
+			return f.String
+		}
+	}
+
+	return ""
+}
+
+// WithTenant returns a context with the Tenant field.
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return WithFields(ctx, zap.String(Tenant, tenant))
+}
+
+// WithTraceId returns a context with the TraceId field.
+func WithTraceId(ctx context.Context, id string) context.Context {
+	return WithFields(ctx, zap.String(TraceId, id))
+}
+
+// WithSpanId returns a context with the SpanId field.
+func WithSpanId(ctx context.Context, id string) context.Context {
+	return WithFields(ctx, zap.String(SpanId, id))
+}
+
+// requestIdKey is the key of the request ID
+type requestIdKey struct{}
+
+// CtxWithRequestId returns a context carrying the request ID, so it can be propagated to outgoing calls.
+// It is not a context field: the context logger is expected to have it already (see Zap.WithRequestId).
+func CtxWithRequestId(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIdKey{}, id)
+}
+
+// RequestIdFromContext returns the request ID of the context, an empty string if it has none.
+func RequestIdFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIdKey{}).(string)
+
+	return id
+}
+
+// maxRequestIdLen is the maximum length of a request ID accepted by ValidRequestId.
+const maxRequestIdLen = 128
+
+// ValidRequestId reports whether the request ID received from a client is safe to log
+// and send back: 1 to 128 ASCII letters, digits, '.', '_' or '-'.
+func ValidRequestId(id string) bool {
+	if id == "" || len(id) > maxRequestIdLen {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		switch c := id[i]; {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '.', c == '_', c == '-':
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+// NewRequestId returns a random request ID of 16 bytes hex encoded.
+func NewRequestId() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,67 @@
+package log
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestWithFields(t *testing.T) {
+	logger, lines := newFileZap(t, Config{DisableCaller: true})
+
+	ctx := CtxWithLogger(context.Background(), logger)
+	ctx = WithTenant(WithUserId(ctx, "u1"), "acme")
+	child := WithFields(WithUserId(ctx, "u2"), zap.Int("attempt", 1), zap.Int("attempt", 2))
+
+	FromContext(ctx).Info("parent")
+	FromContext(child).Info("child")
+	FromContext(WithSpanId(WithTraceId(context.Background(), "t1"), "s1")).Info("no logger")
+	SlogFromContext(child).Info("slog")
+
+	got := lines()
+	require.Len(t, got, 3)
+	require.Contains(t, got[0], `"msg":"parent","userId":"u1","tenant":"acme"}`)
+	require.Contains(t, got[1], `"msg":"child","tenant":"acme","userId":"u2","attempt":2}`)
+	require.Contains(t, got[2], `"msg":"slog","tenant":"acme","userId":"u2","attempt":2}`)
+
+	require.Len(t, ContextFields(ctx), 2)
+	require.Nil(t, ContextFields(context.Background()))
+	require.Equal(t, ctx, WithFields(ctx))
+
+	require.Equal(t, "u1", UserIdFromContext(ctx))
+	require.Equal(t, "u2", UserIdFromContext(child))
+	require.Empty(t, UserIdFromContext(context.Background()))
+}
+
+func TestValidRequestId(t *testing.T) {
+	require.True(t, ValidRequestId("0af7651916cd43dd8448eb211c80319c"))
+	require.True(t, ValidRequestId("req_1.a-B"))
+	require.True(t, ValidRequestId(strings.Repeat("a", 128)))
+	require.False(t, ValidRequestId(""))
+	require.False(t, ValidRequestId(strings.Repeat("a", 129)))
+	require.False(t, ValidRequestId("r1\nr2"))
+	require.False(t, ValidRequestId("r1 r2"))
+	require.False(t, ValidRequestId("ид"))
+}
+
+func TestFromContextReplacesFields(t *testing.T) {
+	logger, lines := newFileZap(t, Config{DisableCaller: true})
+
+	ctx := WithUserId(CtxWithLogger(context.Background(), logger), "u1")
+	parent := FromContext(ctx).WithRequestId("r1")
+	child := WithUserId(CtxWithLogger(ctx, parent), "u2")
+
+	FromContext(child).Info("child")
+	FromContext(CtxWithLogger(ctx, parent)).Info("parent")
+
+	got := lines()
+	require.Len(t, got, 2)
+	require.Equal(t, 1, strings.Count(got[0], `"userId"`))
+	require.Contains(t, got[0], `"userId":"u2"`)
+	require.Contains(t, got[0], `"reqId":"r1"`)
+	require.Equal(t, 1, strings.Count(got[1], `"userId"`))
+	require.Contains(t, got[1], `"userId":"u1"`)
+}
@@ -0,0 +1,241 @@
+// Package grpclog provides gRPC server and client interceptors logging calls with log.Zap.
+package grpclog
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/sorohimm/utils/log"
+)
+
+// MetadataRequestId is the default metadata key of the request ID.
+const MetadataRequestId = "x-request-id"
+
+// Params of the interceptors, the zero value is ready to use.
+type Params struct {
+	// Key is the metadata key of the request ID, MetadataRequestId if empty
+	Key string
+	// Exclude holds the full methods not logged, e.g. "/grpc.health.v1.Health/Check".
+	// Their calls still get a request ID.
+	Exclude []string
+	// NewRequestId generates the ID of a call without a valid one (see log.ValidRequestId),
+	// log.NewRequestId if nil
+	NewRequestId func() string
+	// Level maps the code of a call to the level it is logged at, CodeToLevel if nil
+	Level func(codes.Code) zapcore.Level
+}
+
+type options struct {
+	key     string
+	exclude map[string]struct{}
+	newId   func() string
+	level   func(codes.Code) zapcore.Level
+}
+
+func (p Params) options() options {
+	o := options{key: p.Key, exclude: make(map[string]struct{}, len(p.Exclude)), newId: p.NewRequestId, level: p.Level}
+	if o.key == "" {
+		o.key = MetadataRequestId
+	}
+	if o.newId == nil {
+		o.newId = log.NewRequestId
+	}
+	if o.level == nil {
+		o.level = CodeToLevel
+	}
+	for _, m := range p.Exclude {
+		o.exclude[m] = struct{}{}
+	}
+
+	return o
+}
+
+// CodeToLevel logs client faults and successful calls at info level, calls that may succeed
+// when retried at warn level and server faults at error level.
+func CodeToLevel(code codes.Code) zapcore.Level {
+	switch code {
+	case codes.OK, codes.Canceled, codes.InvalidArgument, codes.NotFound, codes.AlreadyExists, codes.Unauthenticated:
+		return zapcore.InfoLevel
+	case codes.DeadlineExceeded, codes.PermissionDenied, codes.ResourceExhausted, codes.FailedPrecondition,
+		codes.Aborted, codes.OutOfRange, codes.Unavailable:
+		return zapcore.WarnLevel
+	}
+
+	return zapcore.ErrorLevel
+}
+
+// serverContext returns the context of an incoming call with the request ID from the metadata
+// or a generated one if it is missing or invalid and the logger with it, the request ID is sent back in the header.
+func (o options) serverContext(ctx context.Context, logger *log.Zap) (context.Context, *log.Zap) {
+	var id string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if v := md.Get(o.key); len(v) > 0 {
+			id = v[0]
+		}
+	}
+	if !log.ValidRequestId(id) {
+		id = o.newId()
+	}
+	_ = grpc.SetHeader(ctx, metadata.Pairs(o.key, id))
+
+	reqLogger := logger.WithRequestId(id)
+
+	return log.CtxWithLogger(log.CtxWithRequestId(ctx, id), reqLogger), reqLogger
+}
+
+// clientContext returns the context of an outgoing call with the request ID of the context
+// or a generated one in the metadata.
+func (o options) clientContext(ctx context.Context) (context.Context, string) {
+	if md, ok := metadata.FromOutgoingContext(ctx); ok {
+		if v := md.Get(o.key); len(v) > 0 {
+			return ctx, v[0]
+		}
+	}
+
+	id := log.RequestIdFromContext(ctx)
+	if id == "" {
+		id = o.newId()
+	}
+
+	return metadata.AppendToOutgoingContext(ctx, o.key, id), id
+}
+
+func (o options) log(logger *log.Zap, method string, err error, start time.Time, fields ...zap.Field) {
+	if _, ok := o.exclude[method]; ok {
+		return
+	}
+
+	code := status.Code(err)
+	fields = append(fields,
+		zap.String("method", method),
+		zap.String("code", code.String()),
+		zap.Duration("duration", time.Since(start)),
+	)
+	if err != nil {
+		fields = append(fields, zap.Error(err))
+	}
+	logger.Check(o.level(code), "call").Write(fields...)
+}
+
+func peerField(ctx context.Context) zap.Field {
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		return zap.String("peer", p.Addr.String())
+	}
+
+	return zap.Skip()
+}
+
+// UnaryServerInterceptor returns a server interceptor propagating the request ID from the metadata
+// or generating one, putting the logger with it into the call context (see log.FromContext)
+// and logging the call with the method, the code, the duration and the peer.
+func UnaryServerInterceptor(logger *log.Zap, params Params) grpc.UnaryServerInterceptor {
+	o := params.options()
+
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		ctx, reqLogger := o.serverContext(ctx, logger)
+
+		resp, err := handler(ctx, req)
+		o.log(reqLogger, info.FullMethod, err, start, peerField(ctx))
+
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor is the streaming counterpart of UnaryServerInterceptor,
+// the call is logged once the stream is finished.
+func StreamServerInterceptor(logger *log.Zap, params Params) grpc.StreamServerInterceptor {
+	o := params.options()
+
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		ctx, reqLogger := o.serverContext(ss.Context(), logger)
+
+		err := handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+		o.log(reqLogger, info.FullMethod, err, start, peerField(ctx))
+
+		return err
+	}
+}
+
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+// UnaryClientInterceptor returns a client interceptor sending the request ID of the context
+// (see log.CtxWithRequestId) or a generated one in the metadata and logging the call
+// with the context logger, the method, the code, the duration and the target.
+func UnaryClientInterceptor(params Params) grpc.UnaryClientInterceptor {
+	o := params.options()
+
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		start := time.Now()
+		ctx, id := o.clientContext(ctx)
+
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		o.log(log.FromContext(ctx).WithRequestId(id), method, err, start, zap.String("peer", cc.Target()))
+
+		return err
+	}
+}
+
+// StreamClientInterceptor is the streaming counterpart of UnaryClientInterceptor,
+// the call is logged once the stream is finished or fails.
+func StreamClientInterceptor(params Params) grpc.StreamClientInterceptor {
+	o := params.options()
+
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		start := time.Now()
+		ctx, id := o.clientContext(ctx)
+		logger := log.FromContext(ctx).WithRequestId(id)
+		target := zap.String("peer", cc.Target())
+
+		cs, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			o.log(logger, method, err, start, target)
+			return nil, err
+		}
+
+		return &clientStream{ClientStream: cs, done: func(err error) {
+			o.log(logger, method, err, start, target)
+		}}, nil
+	}
+}
+
+// clientStream reports the end of the stream: the first error of RecvMsg, io.EOF on success.
+type clientStream struct {
+	grpc.ClientStream
+	once sync.Once
+	done func(error)
+}
+
+func (s *clientStream) RecvMsg(m any) error {
+	err := s.ClientStream.RecvMsg(m)
+	if err != nil {
+		s.once.Do(func() {
+			if errors.Is(err, io.EOF) {
+				s.done(nil)
+				return
+			}
+			s.done(err)
+		})
+	}
+
+	return err
+}
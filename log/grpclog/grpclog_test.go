@@ -0,0 +1,199 @@
+package grpclog
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/sorohimm/utils/log"
+)
+
+const (
+	methodCheck = "/grpc.health.v1.Health/Check"
+	methodWatch = "/grpc.health.v1.Health/Watch"
+)
+
+// newClient starts an in-process health server and returns a client of it, a context
+// with the client logger and the entries logged by both sides.
+func newClient(t *testing.T, params Params) (healthpb.HealthClient, context.Context, *observer.ObservedLogs) {
+	t.Helper()
+
+	core, logs := observer.New(zapcore.DebugLevel)
+	logger := &log.Zap{Logger: zap.New(core).Named("server")}
+
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer(
+		grpc.UnaryInterceptor(UnaryServerInterceptor(logger, params)),
+		grpc.StreamInterceptor(StreamServerInterceptor(logger, params)),
+	)
+	hs := health.NewServer()
+	hs.SetServingStatus("svc", healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(srv, hs)
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithUnaryInterceptor(UnaryClientInterceptor(params)),
+		grpc.WithStreamInterceptor(StreamClientInterceptor(params)),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	ctx := log.CtxWithLogger(context.Background(), &log.Zap{Logger: zap.New(core).Named("client")})
+
+	return healthpb.NewHealthClient(conn), ctx, logs
+}
+
+func entry(t *testing.T, logs *observer.ObservedLogs, name, method string) observer.LoggedEntry {
+	t.Helper()
+
+	var res []observer.LoggedEntry
+	require.Eventually(t, func() bool {
+		res = logs.Filter(func(e observer.LoggedEntry) bool {
+			return e.LoggerName == name && e.ContextMap()["method"] == method
+		}).AllUntimed()
+		return len(res) > 0
+	}, time.Second, 10*time.Millisecond)
+	require.Len(t, res, 1)
+
+	return res[0]
+}
+
+func TestUnary(t *testing.T) {
+	client, ctx, logs := newClient(t, Params{})
+
+	var header metadata.MD
+	_, err := client.Check(log.CtxWithRequestId(ctx, "r1"), &healthpb.HealthCheckRequest{Service: "svc"}, grpc.Header(&header))
+	require.NoError(t, err)
+	require.Equal(t, []string{"r1"}, header.Get(MetadataRequestId))
+
+	srv := entry(t, logs, "server", methodCheck)
+	require.Equal(t, zapcore.InfoLevel, srv.Level)
+	require.Equal(t, "call", srv.Message)
+	require.Equal(t, "r1", srv.ContextMap()[log.RequestId])
+	require.Equal(t, "OK", srv.ContextMap()["code"])
+	require.Equal(t, "bufconn", srv.ContextMap()["peer"])
+	require.Contains(t, srv.ContextMap(), "duration")
+
+	cl := entry(t, logs, "client", methodCheck)
+	require.Equal(t, "r1", cl.ContextMap()[log.RequestId])
+	require.Equal(t, "passthrough:///bufnet", cl.ContextMap()["peer"])
+
+	logs.TakeAll()
+	_, err = client.Check(ctx, &healthpb.HealthCheckRequest{Service: "unknown"})
+	require.Equal(t, codes.NotFound, status.Code(err))
+
+	srv = entry(t, logs, "server", methodCheck)
+	require.Equal(t, "NotFound", srv.ContextMap()["code"])
+	require.NotEmpty(t, srv.ContextMap()[log.RequestId])
+	require.Equal(t, srv.ContextMap()[log.RequestId], entry(t, logs, "client", methodCheck).ContextMap()[log.RequestId])
+
+	logs.TakeAll()
+	header = nil
+	_, err = client.Check(log.CtxWithRequestId(ctx, "r1 r2;drop"), &healthpb.HealthCheckRequest{Service: "svc"}, grpc.Header(&header))
+	require.NoError(t, err)
+	id := entry(t, logs, "server", methodCheck).ContextMap()[log.RequestId]
+	require.NotEqual(t, "r1 r2;drop", id)
+	require.True(t, log.ValidRequestId(id.(string)))
+	require.Equal(t, []string{id.(string)}, header.Get(MetadataRequestId))
+}
+
+func TestStream(t *testing.T) {
+	client, ctx, logs := newClient(t, Params{
+		NewRequestId: func() string { return "generated" },
+		Level: func(code codes.Code) zapcore.Level {
+			if code == codes.Canceled {
+				return zapcore.DebugLevel
+			}
+			return CodeToLevel(code)
+		},
+	})
+
+	ctx, cancel := context.WithCancel(ctx)
+	stream, err := client.Watch(ctx, &healthpb.HealthCheckRequest{Service: "svc"})
+	require.NoError(t, err)
+	resp, err := stream.Recv()
+	require.NoError(t, err)
+	require.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.GetStatus())
+
+	cancel()
+	_, err = stream.Recv()
+	require.Equal(t, codes.Canceled, status.Code(err))
+
+	cl := entry(t, logs, "client", methodWatch)
+	require.Equal(t, zapcore.DebugLevel, cl.Level)
+	require.Equal(t, "generated", cl.ContextMap()[log.RequestId])
+	require.Equal(t, "Canceled", cl.ContextMap()["code"])
+
+	srv := entry(t, logs, "server", methodWatch)
+	require.Equal(t, "generated", srv.ContextMap()[log.RequestId])
+}
+
+func TestClientInServer(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	logger := &log.Zap{Logger: zap.New(core)}
+
+	cc, err := grpc.NewClient("passthrough:///downstream", grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = cc.Close() })
+
+	var sent []string
+	client := UnaryClientInterceptor(Params{})
+	invoker := func(ctx context.Context, _ string, _, _ any, _ *grpc.ClientConn, _ ...grpc.CallOption) error {
+		md, _ := metadata.FromOutgoingContext(ctx)
+		sent = md.Get(MetadataRequestId)
+		return nil
+	}
+	handler := func(ctx context.Context, _ any) (any, error) {
+		return nil, client(ctx, "/downstream/Call", nil, nil, cc, invoker)
+	}
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(MetadataRequestId, "r1"))
+	_, err = UnaryServerInterceptor(logger, Params{})(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/upstream/Call"}, handler)
+	require.NoError(t, err)
+	require.Equal(t, []string{"r1"}, sent)
+
+	entries := logs.AllUntimed()
+	require.Len(t, entries, 2)
+	for _, e := range entries {
+		var ids []string
+		for _, f := range e.Context {
+			if f.Key == log.RequestId {
+				ids = append(ids, f.String)
+			}
+		}
+		require.Equal(t, []string{"r1"}, ids, e.ContextMap()["method"])
+	}
+}
+
+func TestExclude(t *testing.T) {
+	client, ctx, logs := newClient(t, Params{Exclude: []string{methodCheck}})
+
+	_, err := client.Check(ctx, &healthpb.HealthCheckRequest{Service: "svc"})
+	require.NoError(t, err)
+	require.Zero(t, logs.Len())
+}
+
+func TestCodeToLevel(t *testing.T) {
+	require.Equal(t, zapcore.InfoLevel, CodeToLevel(codes.OK))
+	require.Equal(t, zapcore.WarnLevel, CodeToLevel(codes.Unavailable))
+	require.Equal(t, zapcore.ErrorLevel, CodeToLevel(codes.Internal))
+}
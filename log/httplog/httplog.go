@@ -0,0 +1,180 @@
+// Package httplog provides HTTP server middleware logging requests with log.Zap.
+package httplog
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/sorohimm/utils/log"
+)
+
+// HeaderRequestId is the default header of the request ID.
+const HeaderRequestId = "X-Request-Id"
+
+// Params of the middleware, the zero value is ready to use.
+type Params struct {
+	// Header carries the request ID, HeaderRequestId if empty
+	Header string
+	// Exclude holds the paths not logged, e.g. health checks. Their requests still get a request ID.
+	Exclude []string
+	// NewRequestId generates the ID of a request without a valid one (see log.ValidRequestId),
+	// log.NewRequestId if nil
+	NewRequestId func() string
+}
+
+// Middleware returns a middleware that propagates the request ID from the header or generates one
+// if it is missing or invalid (see log.ValidRequestId),
+// puts the request ID and the logger with it into the request context (see log.FromContext) and logs
+// the request once it is served: at error level for 5xx statuses, warn for 4xx and info otherwise.
+// A panic of the handler is recovered and logged at error level with the stack trace.
+//
+//	mux := http.NewServeMux()
+//	srv := &http.Server{Handler: httplog.Middleware(logger, httplog.Params{Exclude: []string{"/healthz"}})(mux)}
+func Middleware(logger *log.Zap, params Params) func(http.Handler) http.Handler {
+	header := params.Header
+	if header == "" {
+		header = HeaderRequestId
+	}
+	newId := params.NewRequestId
+	if newId == nil {
+		newId = log.NewRequestId
+	}
+	exclude := make(map[string]struct{}, len(params.Exclude))
+	for _, p := range params.Exclude {
+		exclude[p] = struct{}{}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := r.Header.Get(header)
+			if !log.ValidRequestId(id) {
+				id = newId()
+			}
+			w.Header().Set(header, id)
+
+			reqLogger := logger.WithRequestId(id)
+			r = r.WithContext(log.CtxWithLogger(log.CtxWithRequestId(r.Context(), id), reqLogger))
+
+			rec := &recorder{ResponseWriter: w}
+			start := time.Now()
+			_, excluded := exclude[r.URL.Path]
+
+			defer func() {
+				p := recover()
+				if p == nil {
+					if !excluded {
+						reqLogger.Check(level(rec.code()), "request").Write(fields(r, rec, start)...)
+					}
+					return
+				}
+				if p == http.ErrAbortHandler { //nolint:errorlint
+					panic(p)
+				}
+
+				if !rec.written() {
+					rec.WriteHeader(http.StatusInternalServerError)
+				}
+				reqLogger.Error("request panic",
+					append(fields(r, rec, start), zap.Any("panic", p), zap.StackSkip("stack", 1))...)
+			}()
+
+			next.ServeHTTP(rec, r)
+		})
+	}
+}
+
+func fields(r *http.Request, rec *recorder, start time.Time) []zap.Field {
+	return []zap.Field{
+		zap.String("method", r.Method),
+		zap.String("path", r.URL.Path),
+		zap.Int("status", rec.code()),
+		zap.Int64("bytes", rec.bytes),
+		zap.Duration("duration", time.Since(start)),
+		zap.String("remote", r.RemoteAddr),
+	}
+}
+
+func level(status int) zapcore.Level {
+	switch {
+	case status >= http.StatusInternalServerError:
+		return zapcore.ErrorLevel
+	case status >= http.StatusBadRequest:
+		return zapcore.WarnLevel
+	}
+
+	return zapcore.InfoLevel
+}
+
+// recorder records the status and the size of the response.
+type recorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (r *recorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	n, err := r.ResponseWriter.Write(b)
+	r.bytes += int64(n)
+
+	return n, err
+}
+
+func (r *recorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		if r.status == 0 {
+			r.status = http.StatusOK
+		}
+		f.Flush()
+	}
+}
+
+// Hijack lets upgrade handlers (e.g. WebSocket) take over the connection, the request is logged
+// with the 101 status unless a status was written before.
+func (r *recorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := r.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("hijack error: %w", http.ErrNotSupported)
+	}
+	conn, rw, err := h.Hijack()
+	if err != nil {
+		return nil, nil, err
+	}
+	if r.status == 0 {
+		r.status = http.StatusSwitchingProtocols
+	}
+
+	return conn, rw, nil
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (r *recorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+func (r *recorder) written() bool {
+	return r.status != 0
+}
+
+func (r *recorder) code() int {
+	if r.status == 0 {
+		return http.StatusOK
+	}
+
+	return r.status
+}
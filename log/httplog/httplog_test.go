@@ -0,0 +1,128 @@
+package httplog
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/sorohimm/utils/log"
+)
+
+func TestMiddleware(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	logger := &log.Zap{Logger: zap.New(core)}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {
+		log.FromContext(r.Context()).Info("handler", zap.String("id", log.RequestIdFromContext(r.Context())))
+		_, _ = w.Write([]byte("hello"))
+	})
+	mux.HandleFunc("/missing", func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	})
+	mux.HandleFunc("/panic", func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {})
+
+	h := Middleware(logger, Params{
+		Exclude:      []string{"/healthz"},
+		NewRequestId: func() string { return "generated" },
+	})(mux)
+
+	serve := func(path, id string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if id != "" {
+			req.Header.Set(HeaderRequestId, id)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w
+	}
+
+	w := serve("/ok", "r1")
+	require.Equal(t, "r1", w.Header().Get(HeaderRequestId))
+	require.Equal(t, "hello", w.Body.String())
+
+	w = serve("/missing", "")
+	require.Equal(t, "generated", w.Header().Get(HeaderRequestId))
+
+	w = serve("/panic", "r3")
+	require.Equal(t, http.StatusInternalServerError, w.Code)
+
+	serve("/healthz", "r4")
+	for _, id := range []string{"r5\r\nX-Injected: 1", "r5 r6", strings.Repeat("r", 129)} {
+		w = serve("/healthz", id)
+		require.Equal(t, "generated", w.Header().Get(HeaderRequestId))
+	}
+
+	entries := logs.AllUntimed()
+	require.Len(t, entries, 4)
+
+	require.Equal(t, "handler", entries[0].Message)
+	require.Equal(t, "r1", entries[0].ContextMap()[log.RequestId])
+	require.Equal(t, "r1", entries[0].ContextMap()["id"])
+
+	fields := entries[1].ContextMap()
+	require.Equal(t, zapcore.InfoLevel, entries[1].Level)
+	require.Equal(t, "request", entries[1].Message)
+	require.Equal(t, "r1", fields[log.RequestId])
+	require.Equal(t, "GET", fields["method"])
+	require.Equal(t, "/ok", fields["path"])
+	require.Equal(t, int64(200), fields["status"])
+	require.Equal(t, int64(5), fields["bytes"])
+	require.Contains(t, fields, "duration")
+	require.Contains(t, fields, "remote")
+
+	require.Equal(t, zapcore.WarnLevel, entries[2].Level)
+	require.Equal(t, "generated", entries[2].ContextMap()[log.RequestId])
+	require.Equal(t, int64(404), entries[2].ContextMap()["status"])
+
+	require.Equal(t, zapcore.ErrorLevel, entries[3].Level)
+	require.Equal(t, "request panic", entries[3].Message)
+	require.Equal(t, "boom", entries[3].ContextMap()["panic"])
+	require.Contains(t, entries[3].ContextMap()["stack"], "httplog_test.go")
+}
+
+func TestHijack(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	logger := &log.Zap{Logger: zap.New(core)}
+
+	srv := httptest.NewServer(Middleware(logger, Params{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, rw, err := w.(http.Hijacker).Hijack()
+		require.NoError(t, err)
+		defer conn.Close()
+		_, _ = rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: test\r\nConnection: Upgrade\r\n\r\n")
+		_ = rw.Flush()
+	})))
+	defer srv.Close()
+
+	req, err := http.NewRequest(http.MethodGet, srv.URL, nil)
+	require.NoError(t, err)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "test")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	require.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+
+	require.Eventually(t, func() bool { return logs.Len() == 1 }, time.Second, 10*time.Millisecond)
+	require.Equal(t, int64(http.StatusSwitchingProtocols), logs.All()[0].ContextMap()["status"])
+
+	// the recorder of a writer without Hijack reports it is not supported
+	_, _, err = (&recorder{ResponseWriter: httptest.NewRecorder()}).Hijack()
+	require.ErrorIs(t, err, http.ErrNotSupported)
+}
+
+func TestLevel(t *testing.T) {
+	require.Equal(t, zapcore.InfoLevel, level(http.StatusFound))
+	require.Equal(t, zapcore.WarnLevel, level(http.StatusTooManyRequests))
+	require.Equal(t, zapcore.ErrorLevel, level(http.StatusBadGateway))
+}
@@ -0,0 +1,158 @@
+package log
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// NewLevelController creates a LevelController of the logger level.
+func NewLevelController(logger *Zap) *LevelController {
+	return &LevelController{level: logger.Level()}
+}
+
+// LevelController changes a logger level at runtime: temporarily with a TTL
+// after which the previous level is restored, from an HTTP endpoint or on a signal.
+type LevelController struct {
+	level zap.AtomicLevel
+
+	mu      sync.Mutex
+	timer   *time.Timer
+	base    zapcore.Level // level to revert to when the TTL expires
+	pending bool          // whether a TTL is running
+	gen     uint64        // incremented by Set, a revert of an older Set is stale
+}
+
+// Level returns the current level.
+func (c *LevelController) Level() zapcore.Level {
+	return c.level.Level()
+}
+
+// Set changes the level. With a positive TTL the level is reverted to the current one
+// once the TTL expires, setting a level without a TTL cancels a pending revert.
+func (c *LevelController) Set(l zapcore.Level, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Stop does not stop a revert already waiting for the lock, the generation makes it a no-op
+	c.gen++
+	if c.timer != nil {
+		c.timer.Stop()
+		c.timer = nil
+	}
+	if !c.pending {
+		c.base = c.level.Level()
+	}
+	c.pending = ttl > 0
+	c.level.SetLevel(l)
+
+	if ttl > 0 {
+		base, gen := c.base, c.gen
+		c.timer = time.AfterFunc(ttl, func() {
+			c.revert(gen, base)
+		})
+	}
+}
+
+// revert restores the base level when the TTL of the Set of the generation expires.
+func (c *LevelController) revert(gen uint64, base zapcore.Level) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if gen != c.gen {
+		return
+	}
+	c.pending, c.timer = false, nil
+	c.level.SetLevel(base)
+}
+
+type levelPayload struct {
+	Level *zapcore.Level `json:"level"`
+	TTL   string         `json:"ttl,omitempty"`
+}
+
+// ServeHTTP reports the level on GET and changes it on PUT:
+//
+//	curl -X PUT localhost:8080/log/level -d '{"level":"debug","ttl":"10m"}'
+func (c *LevelController) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var p levelPayload
+		if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("request body error: %w", err))
+			return
+		}
+		if p.Level == nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("level must be set"))
+			return
+		}
+
+		var ttl time.Duration
+		if p.TTL != "" {
+			var err error
+			if ttl, err = time.ParseDuration(p.TTL); err != nil || ttl < 0 {
+				writeError(w, http.StatusBadRequest, fmt.Errorf("invalid ttl %q", p.TTL))
+				return
+			}
+		}
+		c.Set(*p.Level, ttl)
+	default:
+		w.Header().Set("Allow", "GET, PUT")
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("only GET and PUT are supported"))
+		return
+	}
+
+	l := c.Level()
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(levelPayload{Level: &l})
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(struct {
+		Error string `json:"error"`
+	}{err.Error()})
+}
+
+// SignalToggle returns an actor for grace.RunGroup.Add that switches the level to debug
+// on every sig and back to the previous level on the next one:
+//
+//	g.Add(NewLevelController(logger).SignalToggle(syscall.SIGUSR1))
+func (c *LevelController) SignalToggle(sig os.Signal) (func() error, func(error)) {
+	sigs := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(sigs, sig)
+
+	exec := func() error {
+		defer signal.Stop(sigs)
+
+		var previous zapcore.Level
+		for {
+			select {
+			case <-sigs:
+				if l := c.Level(); l != zapcore.DebugLevel {
+					previous = l
+					c.Set(zapcore.DebugLevel, 0)
+					continue
+				}
+				c.Set(previous, 0)
+			case <-done:
+				return nil
+			}
+		}
+	}
+	interrupt := func(error) {
+		close(done)
+	}
+
+	return exec, interrupt
+}
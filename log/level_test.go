@@ -0,0 +1,93 @@
+package log
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestLevelController(t *testing.T) {
+	logger, err := NewZap("info", "json")
+	require.NoError(t, err)
+	c := NewLevelController(logger)
+
+	serve := func(method, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		c.ServeHTTP(rec, httptest.NewRequest(method, "/", strings.NewReader(body)))
+		return rec
+	}
+
+	rec := serve(http.MethodGet, "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.JSONEq(t, `{"level":"info"}`, rec.Body.String())
+
+	rec = serve(http.MethodPut, `{"level":"debug","ttl":"50ms"}`)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.JSONEq(t, `{"level":"debug"}`, rec.Body.String())
+	require.True(t, logger.Core().Enabled(zapcore.DebugLevel))
+
+	rec = serve(http.MethodPut, `{"level":"error","ttl":"50ms"}`)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Eventually(t, func() bool {
+		return logger.Level().Level() == zapcore.InfoLevel
+	}, time.Second, 10*time.Millisecond)
+
+	require.Equal(t, http.StatusBadRequest, serve(http.MethodPut, `{"level":"loud"}`).Code)
+	require.Equal(t, http.StatusBadRequest, serve(http.MethodPut, `{"level":"warn","ttl":"soon"}`).Code)
+	require.Equal(t, http.StatusMethodNotAllowed, serve(http.MethodPost, `{}`).Code)
+}
+
+func TestLevelControllerStaleRevert(t *testing.T) {
+	logger, err := NewZap("info", "json")
+	require.NoError(t, err)
+	c := NewLevelController(logger)
+
+	c.Set(zapcore.DebugLevel, time.Hour)
+	c.Set(zapcore.WarnLevel, time.Hour)
+
+	// the revert of the first Set fired before the second one stopped its timer
+	c.revert(1, zapcore.InfoLevel)
+	require.Equal(t, zapcore.WarnLevel, c.Level())
+	require.True(t, c.pending)
+	require.NotNil(t, c.timer)
+
+	c.Set(zapcore.ErrorLevel, 0)
+	require.Equal(t, zapcore.ErrorLevel, c.Level())
+	require.Nil(t, c.timer)
+
+	c.Set(zapcore.DebugLevel, time.Millisecond)
+	require.Eventually(t, func() bool {
+		return c.Level() == zapcore.ErrorLevel
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestSignalToggle(t *testing.T) {
+	logger, err := NewZap("warn", "json")
+	require.NoError(t, err)
+	c := NewLevelController(logger)
+
+	exec, interrupt := c.SignalToggle(syscall.SIGUSR1)
+	done := make(chan error)
+	go func() {
+		done <- exec()
+	}()
+
+	require.NoError(t, syscall.Kill(syscall.Getpid(), syscall.SIGUSR1))
+	require.Eventually(t, func() bool {
+		return c.Level() == zapcore.DebugLevel
+	}, time.Second, 10*time.Millisecond)
+
+	require.NoError(t, syscall.Kill(syscall.Getpid(), syscall.SIGUSR1))
+	require.Eventually(t, func() bool {
+		return c.Level() == zapcore.WarnLevel
+	}, time.Second, 10*time.Millisecond)
+
+	interrupt(nil)
+	require.NoError(t, <-done)
+}
@@ -6,14 +6,20 @@ import (
 	stdl "log"
 	"sync"
 
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
+
+	"github.com/sorohimm/utils/cfg/types"
 )
 
 const (
 	UserId    = "userId"
 	RequestId = "reqId"
 	Source    = "source"
+	Tenant    = "tenant"
+	TraceId   = "trace_id"
+	SpanId    = "span_id"
 )
 
 var (
@@ -38,52 +44,88 @@ func GetGlobal() *Zap {
 // encType: encoding type of the logger (json, console, etc)
 // Returns: new instance of zap logger and error if any
 func NewZap(lvl, encType string) (*Zap, error) {
-	var (
-		err    error
-		logger *zap.Logger
-	)
-
-	config := zap.NewProductionConfig()
-	config.Encoding = encType
-	config.EncoderConfig.EncodeLevel = zapcore.CapitalLevelEncoder
-	config.EncoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
-	config.EncoderConfig.EncodeDuration = zapcore.StringDurationEncoder
-	config.EncoderConfig.LevelKey = "level"
-	config.EncoderConfig.CallerKey = "c"
-	config.EncoderConfig.StacktraceKey = "s"
-
-	if err = config.Level.UnmarshalText([]byte(lvl)); err != nil && lvl != "" {
-		return nil, err
+	config := DefaultConfig()
+	if lvl != "" {
+		level, err := types.ParseLogLevel(lvl)
+		if err != nil {
+			return nil, err
+		}
+		config.Level = level
 	}
+	config.Encoding = encType
 
-	if logger, err = config.Build(); err != nil {
-		return nil, err
-	}
+	return New(config)
+}
 
-	return &Zap{logger}, nil
+// NewFromCore creates a logger writing to the core. The level is the one Level returns,
+// it is expected to be the level enabler of the core.
+func NewFromCore(core zapcore.Core, level zap.AtomicLevel, opts ...zap.Option) *Zap {
+	return &Zap{Logger: zap.New(core, opts...), level: level}
 }
 
 type Zap struct {
 	*zap.Logger
+	level      zap.AtomicLevel
+	levels     *Levels
+	component  string
+	trace      bool            // see WithTraceContext
+	spanEvents bool            // see WithSpanEvents
+	files      []*RotatingFile // see Reopen
+	async      *AsyncWriter
+	redact     *redactor // see RedactConfig, applied to span events as well
+
+	// added are the context fields FromContext enriched base with and the request ID,
+	// so the logger is rebuilt from base when one of them is replaced (see withFields and derive)
+	added []zap.Field
+	base  *zap.Logger
+	span  trace.Span
 }
 
-func (o *Zap) WithServerOptions(name, version, built string) *Zap {
-	return &Zap{
-		Logger: o.WithOptions(zap.AddStacktrace(zapcore.DPanicLevel)).
-			With(zap.String("v", version), zap.String("built", built), zap.String("app", name)),
+// with returns a Zap wrapping the logger and sharing the rest of o.
+// It is meant for a copy of o, use derive for a logger derived from o.Logger.
+func (o *Zap) with(logger *zap.Logger) *Zap {
+	c := *o
+	c.Logger = logger
+
+	return &c
+}
+
+// derive returns a Zap wrapping the logger derived by fn from o.Logger.
+// The logger without the context fields is derived the same way, so FromContext can rebuild it.
+func (o *Zap) derive(fn func(*zap.Logger) *zap.Logger) *Zap {
+	c := o.with(fn(o.Logger))
+	if o.base != nil {
+		c.base = fn(o.base)
 	}
+
+	return c
+}
+
+// Level returns the atomic level of the logger. It is shared with the loggers derived from it,
+// so changing it at runtime changes the level of all of them (see LevelController).
+func (o *Zap) Level() zap.AtomicLevel {
+	return o.level
 }
 
+func (o *Zap) WithServerOptions(name, version, built string) *Zap {
+	return o.derive(func(l *zap.Logger) *zap.Logger {
+		return l.WithOptions(zap.AddStacktrace(zapcore.DPanicLevel)).
+			With(zap.String("v", version), zap.String("built", built), zap.String("app", name))
+	})
+}
+
+// WithSource returns a logger of the source component, its level is resolved by component
+// levels (see Levels), e.g. WithSource("db.pool") logs at the level set for "db.pool" or "db".
 func (o *Zap) WithSource(source string) *Zap {
-	return &Zap{
-		Logger: o.Logger.With(zap.String(Source, source)),
-	}
+	return o.withComponent(func(l *zap.Logger) *zap.Logger {
+		return l.With(zap.String(Source, source))
+	}, source)
 }
 
+// WithRequestId returns the logger with the RequestId field, replacing the one the logger already has,
+// so a client call logged with the logger of a server call has a single request ID.
 func (o *Zap) WithRequestId(id string) *Zap {
-	return &Zap{
-		Logger: o.Logger.With(zap.String(RequestId, id)),
-	}
+	return o.withFields([]zap.Field{zap.String(RequestId, id)})
 }
 
 // ctxKey is a struct that is used as the key for storing logger in the context
@@ -94,11 +136,41 @@ func CtxWithLogger(ctx context.Context, logger *Zap) context.Context {
 	return context.WithValue(ctx, ctxKey{}, logger)
 }
 
-// FromContext retrieves the logger from the context, returns a Nop logger if not found
+// FromContext retrieves the logger from the context, returns a Nop logger if not found.
+// The logger is enriched with the context fields (see WithFields) and the span of the context
+// if it is enabled (see WithTraceContext and WithSpanEvents).
 func FromContext(ctx context.Context) *Zap {
-	if ctxLogger, ok := ctx.Value(ctxKey{}).(*Zap); ok {
-		return ctxLogger
+	logger, ok := ctx.Value(ctxKey{}).(*Zap)
+	if !ok {
+		return &Zap{Logger: zap.NewNop(), level: zap.NewAtomicLevel()}
+	}
+
+	fields := ContextFields(ctx)
+	if logger.trace {
+		fields = mergeFields(fields, traceFields(ctx))
+	}
+	if logger.spanEvents {
+		logger = logger.withSpan(ctx)
+	}
+
+	return logger.withFields(fields)
+}
+
+// withFields returns the logger with the fields replacing the added ones of the same key,
+// e.g. a logger enriched with the userId of the parent context and put into a child context
+// with another one. The logger is rebuilt from base, so every key is written once.
+func (o *Zap) withFields(fields []zap.Field) *Zap {
+	added := mergeFields(o.added, fields)
+	if sameFields(added, o.added) {
+		return o
+	}
+
+	base := o.base
+	if base == nil {
+		base = o.Logger
 	}
+	c := o.with(base.With(added...))
+	c.base, c.added = base, added
 
-	return &Zap{zap.NewNop()}
+	return c
 }
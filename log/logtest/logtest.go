@@ -0,0 +1,198 @@
+// Package logtest provides a log.Zap recording its entries for tests and assertions on them.
+package logtest
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/sorohimm/utils/log"
+)
+
+// Params of the test logger, the zero value records every entry.
+type Params struct {
+	// Level is the level of the logger, debug if zero
+	Level zapcore.Level
+	// Mirror writes the entries to t.Log as well, so they are shown for failing tests only
+	// (or with go test -v)
+	Mirror bool
+}
+
+// New creates a logger recording its entries.
+//
+//	logger := logtest.New(t, logtest.Params{Mirror: true})
+//	svc := NewService(logger.Zap)
+//	svc.Do(logger.Context(ctx))
+//	logger.AssertLogged(t, zapcore.InfoLevel, "done", zap.String("id", "1"))
+func New(t testing.TB, params Params) *Logger {
+	t.Helper()
+
+	level := zap.NewAtomicLevelAt(params.Level)
+	core, logs := observer.New(level)
+	if params.Mirror {
+		core = zapcore.NewTee(core, zaptest.NewLogger(t, zaptest.Level(level)).Core())
+	}
+
+	return &Logger{Zap: log.NewFromCore(core, level), logs: logs}
+}
+
+// Logger is a log.Zap recording its entries and the ones of the loggers derived from it.
+type Logger struct {
+	*log.Zap
+	logs *observer.ObservedLogs
+}
+
+// Context returns the context carrying the logger (see log.FromContext).
+func (l *Logger) Context(ctx context.Context) context.Context {
+	return log.CtxWithLogger(ctx, l.Zap)
+}
+
+// Entries returns the recorded entries.
+func (l *Logger) Entries() Entries {
+	return l.logs.All()
+}
+
+// Reset removes the recorded entries.
+func (l *Logger) Reset() {
+	l.logs.TakeAll()
+}
+
+// AssertLogged asserts that an entry with the level, the message and the fields was recorded,
+// the entry may have other fields.
+func (l *Logger) AssertLogged(t testing.TB, level zapcore.Level, msg string, fields ...zap.Field) {
+	t.Helper()
+	l.Entries().AssertLogged(t, level, msg, fields...)
+}
+
+// AssertNotLogged asserts that no entry with the level, the message and the fields was recorded.
+func (l *Logger) AssertNotLogged(t testing.TB, level zapcore.Level, msg string, fields ...zap.Field) {
+	t.Helper()
+	l.Entries().AssertNotLogged(t, level, msg, fields...)
+}
+
+// Entries are recorded entries.
+type Entries []observer.LoggedEntry
+
+// Filter returns the entries matching the predicate.
+func (e Entries) Filter(fn func(observer.LoggedEntry) bool) Entries {
+	var res Entries
+	for _, entry := range e {
+		if fn(entry) {
+			res = append(res, entry)
+		}
+	}
+
+	return res
+}
+
+// ByLevel returns the entries of the level.
+func (e Entries) ByLevel(level zapcore.Level) Entries {
+	return e.Filter(func(entry observer.LoggedEntry) bool {
+		return entry.Level == level
+	})
+}
+
+// ByMessage returns the entries with the message.
+func (e Entries) ByMessage(msg string) Entries {
+	return e.Filter(func(entry observer.LoggedEntry) bool {
+		return entry.Message == msg
+	})
+}
+
+// BySource returns the entries of the source (see log.Zap.WithSource).
+func (e Entries) BySource(source string) Entries {
+	return e.Filter(fieldIs(log.Source, source))
+}
+
+// ByRequestId returns the entries of the request (see log.Zap.WithRequestId).
+func (e Entries) ByRequestId(id string) Entries {
+	return e.Filter(fieldIs(log.RequestId, id))
+}
+
+func fieldIs(key, value string) func(observer.LoggedEntry) bool {
+	return func(entry observer.LoggedEntry) bool {
+		return entry.ContextMap()[key] == value
+	}
+}
+
+// Messages returns the messages of the entries.
+func (e Entries) Messages() []string {
+	res := make([]string, 0, len(e))
+	for _, entry := range e {
+		res = append(res, entry.Message)
+	}
+
+	return res
+}
+
+// AssertLogged asserts that one of the entries has the level, the message and the fields.
+func (e Entries) AssertLogged(t testing.TB, level zapcore.Level, msg string, fields ...zap.Field) {
+	t.Helper()
+
+	if len(e.matching(level, msg, fields)) == 0 {
+		t.Errorf("no %s entry %q%s is logged, logged:\n%s", level, msg, describe(fields), e)
+	}
+}
+
+// AssertNotLogged asserts that none of the entries has the level, the message and the fields.
+func (e Entries) AssertNotLogged(t testing.TB, level zapcore.Level, msg string, fields ...zap.Field) {
+	t.Helper()
+
+	if m := e.matching(level, msg, fields); len(m) > 0 {
+		t.Errorf("%s entry %q%s is logged:\n%s", level, msg, describe(fields), m)
+	}
+}
+
+func (e Entries) matching(level zapcore.Level, msg string, fields []zap.Field) Entries {
+	want := fieldMap(fields)
+
+	return e.Filter(func(entry observer.LoggedEntry) bool {
+		if entry.Level != level || entry.Message != msg {
+			return false
+		}
+		got := entry.ContextMap()
+		for k, v := range want {
+			if gv, ok := got[k]; !ok || !reflect.DeepEqual(gv, v) {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+func fieldMap(fields []zap.Field) map[string]interface{} {
+	enc := zapcore.NewMapObjectEncoder()
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+
+	return enc.Fields
+}
+
+func describe(fields []zap.Field) string {
+	if len(fields) == 0 {
+		return ""
+	}
+
+	return fmt.Sprintf(" with %v", fieldMap(fields))
+}
+
+// String lists the entries one per line.
+func (e Entries) String() string {
+	var b strings.Builder
+	for _, entry := range e {
+		_, _ = fmt.Fprintf(&b, "\t%s %q %v\n", entry.Level, entry.Message, entry.ContextMap())
+	}
+	if b.Len() == 0 {
+		return "\t(none)\n"
+	}
+
+	return b.String()
+}
@@ -0,0 +1,63 @@
+package logtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/sorohimm/utils/log"
+)
+
+// recordingT records the failures of the assertions.
+type recordingT struct {
+	testing.TB
+	errors []string
+}
+
+func (t *recordingT) Helper() {}
+
+func (t *recordingT) Errorf(format string, args ...interface{}) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+func TestLogger(t *testing.T) {
+	logger := New(t, Params{Level: zapcore.InfoLevel, Mirror: true})
+
+	ctx := logger.Context(context.Background())
+	log.FromContext(ctx).WithSource("db").WithRequestId("r1").Info("query", zap.Int("rows", 2), zap.Error(errors.New("slow")))
+	logger.WithRequestId("r2").Warn("retry")
+	logger.Debug("skipped")
+
+	logger.AssertLogged(t, zapcore.InfoLevel, "query")
+	logger.AssertLogged(t, zapcore.InfoLevel, "query", zap.Int("rows", 2), zap.String(log.Source, "db"))
+	logger.AssertLogged(t, zapcore.InfoLevel, "query", zap.Error(errors.New("slow")))
+	logger.AssertNotLogged(t, zapcore.DebugLevel, "skipped")
+	logger.AssertNotLogged(t, zapcore.InfoLevel, "query", zap.Int("rows", 3))
+
+	require.Equal(t, []string{"query"}, logger.Entries().BySource("db").Messages())
+	require.Equal(t, []string{"retry"}, logger.Entries().ByRequestId("r2").Messages())
+	require.Equal(t, []string{"retry"}, logger.Entries().ByLevel(zapcore.WarnLevel).Messages())
+	require.Len(t, logger.Entries().ByMessage("query"), 1)
+
+	logger.Reset()
+	require.Empty(t, logger.Entries())
+}
+
+func TestAssertFailures(t *testing.T) {
+	logger := New(t, Params{})
+	logger.Info("done", zap.String("id", "1"))
+
+	rt := &recordingT{}
+	logger.AssertLogged(rt, zapcore.InfoLevel, "done", zap.String("id", "2"))
+	logger.AssertNotLogged(rt, zapcore.InfoLevel, "done")
+
+	require.Len(t, rt.errors, 2)
+	require.Contains(t, rt.errors[0], `no info entry "done" with map[id:2] is logged`)
+	require.Contains(t, rt.errors[0], `info "done" map[id:1]`)
+	require.Contains(t, rt.errors[1], `info entry "done" is logged`)
+}
@@ -0,0 +1,100 @@
+package log
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/sorohimm/utils/cfg"
+)
+
+func TestRedact(t *testing.T) {
+	logger, lines := newFileZap(t, Config{
+		DisableCaller: true,
+		Redact:        RedactConfig{Enabled: true, Patterns: []string{"bearer", "card", "email", `sk_live_\w+`}},
+	})
+
+	logger.With(zap.String("Authorization", "Bearer abc.def")).Info("request",
+		zap.String("header", "Bearer abc.def"),
+		zap.String("dsn", "postgres://app:hunter2@db:5432/app?sslmode=disable"),
+		zap.Any("safe", cfg.SafeString("supersecretvalue")),
+		zap.Any("key", cfg.NewSecret([]byte("supersecretvalue"))),
+		zap.Stringer("stringer", cfg.SafeString("supersecretvalue")),
+		zap.Error(errors.New("dial postgres://app:hunter2@db failed")),
+		zap.Int("n", 1),
+	)
+	logger.Info("paid with 4111 1111 1111 1111 by john@example.com using sk_live_123")
+
+	got := lines()
+	require.Len(t, got, 2)
+	require.NotContains(t, got[0], "abc.def")
+	require.NotContains(t, got[0], "hunter2")
+	require.NotContains(t, got[0], "supersecretvalue")
+	require.Contains(t, got[0], `"msg":"request","Authorization":"***"`)
+	require.Contains(t, got[0], `"header":"***"`)
+	require.Contains(t, got[0], `"dsn":"postgres://app:***@db:5432/app?sslmode=disable"`)
+	require.Contains(t, got[0], `"safe":"***************value"`)
+	require.Contains(t, got[0], `"key":"***************value"`)
+	require.Contains(t, got[0], `"stringer":"***************value"`)
+	require.Contains(t, got[0], `"error":"dial postgres://app:***@db failed"`)
+	require.Contains(t, got[0], `"n":1`)
+	require.Contains(t, got[1], `"msg":"paid with *** by *** using ***"`)
+}
+
+func TestRedactNilError(t *testing.T) {
+	logger, lines := newFileZap(t, Config{DisableCaller: true, Redact: RedactConfig{Enabled: true}})
+
+	var nilErr *valueError
+	logger.Info("nil", zap.Error(nilErr))
+
+	got := lines()
+	require.Len(t, got, 1)
+	require.Contains(t, got[0], `"error":"<nil>"`)
+}
+
+func TestRedactNested(t *testing.T) {
+	logger, lines := newFileZap(t, Config{
+		DisableCaller: true,
+		Redact:        RedactConfig{Enabled: true, Patterns: []string{"bearer"}},
+	})
+
+	type credentials struct {
+		User     string `json:"user"`
+		Password string `json:"password"`
+		Port     int    `json:"port"`
+	}
+	err := ErrorWithFields(errors.New("dial postgres://app:hunter2@db failed"),
+		zap.String("token", "tok-123"), zap.String("note", "Bearer abc.def"))
+
+	logger.Info("nested",
+		Err(fmt.Errorf("connect: %w", err)),
+		zap.Any("headers", http.Header{"Authorization": {"Bearer abc.def"}, "X-Trace": {"Bearer abc.def", "t1"}}),
+		zap.Any("creds", credentials{User: "app", Password: "hunter2", Port: 5432}),
+		zap.Any("list", []credentials{{User: "app", Password: "hunter2"}}),
+		zap.Any("meta", map[string]interface{}{"dsn": "postgres://app:hunter2@db", "n": 1, "nil": nil}),
+		zap.Objects("errs", []zapcore.ObjectMarshaler{errObject{errors.New("Bearer abc.def")}}),
+	)
+
+	got := lines()
+	require.Len(t, got, 1)
+	require.NotContains(t, got[0], "hunter2")
+	require.NotContains(t, got[0], "abc.def")
+	require.NotContains(t, got[0], "tok-123")
+	require.Contains(t, got[0], `"msg":"connect: dial postgres://app:***@db failed"`)
+	require.Contains(t, got[0], `"fields":{"token":"***","note":"***"}`)
+	require.Contains(t, got[0], `"headers":{"Authorization":"***","X-Trace":["***","t1"]}`)
+	require.Contains(t, got[0], `"creds":{"password":"***","port":5432,"user":"app"}`)
+	require.Contains(t, got[0], `"list":[{"password":"***","port":0,"user":"app"}]`)
+	require.Contains(t, got[0], `"meta":{"dsn":"postgres://app:***@db","n":1,"nil":null}`)
+	require.Contains(t, got[0], `"errs":[{"msg":"***"}]`)
+}
+
+func TestRedactInvalidPattern(t *testing.T) {
+	_, err := New(Config{Redact: RedactConfig{Enabled: true, Patterns: []string{"("}}})
+	require.ErrorContains(t, err, "invalid redact pattern")
+}
@@ -0,0 +1,328 @@
+package log
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/sorohimm/utils/cfg/types"
+)
+
+// backupTimeFormat is the time format of the rotated file names, it sorts chronologically.
+const backupTimeFormat = "2006-01-02T15-04-05.000"
+
+// RotationConfig enables the rotation of the file outputs of Config.OutputPaths:
+// a file is rotated once it would grow beyond MaxSize or it is older than MaxAge.
+// Rotation is disabled when both are zero, the files can still be rotated by an external tool
+// such as logrotate and reopened with Zap.Reopen.
+type RotationConfig struct {
+	MaxSize types.ByteSize `yaml:"maxSize" env:"MAX_SIZE"`
+	MaxAge  time.Duration  `yaml:"maxAge" env:"MAX_AGE"`
+	// MaxBackups is the number of rotated files kept, all of them are kept if zero
+	MaxBackups int `yaml:"maxBackups" env:"MAX_BACKUPS" validate:"min=0"`
+	// Compress gzips the rotated files
+	Compress bool `yaml:"compress" env:"COMPRESS"`
+}
+
+// NewRotatingFile opens the file for appending, creating it if needed.
+func NewRotatingFile(path string, c RotationConfig) (*RotatingFile, error) {
+	f := &RotatingFile{path: path, c: c, now: time.Now}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// RotatingFile is a zapcore.WriteSyncer writing to a file that is rotated by size and age.
+// A rotated file is renamed to name-<time>.ext next to it, compressed and removed
+// in the background according to the config.
+type RotatingFile struct {
+	path string
+	c    RotationConfig
+	now  func() time.Time
+
+	mu     sync.Mutex
+	f      *os.File
+	size   int64
+	opened time.Time
+
+	cleanupMu sync.Mutex
+	wg        sync.WaitGroup
+}
+
+func (o *RotatingFile) Write(p []byte) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.f == nil {
+		if err := o.open(); err != nil {
+			return 0, err
+		}
+	}
+	if o.due(int64(len(p))) {
+		if err := o.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := o.f.Write(p)
+	o.size += int64(n)
+
+	return n, err
+}
+
+func (o *RotatingFile) due(n int64) bool {
+	if o.c.MaxSize > 0 && o.size > 0 && o.size+n > o.c.MaxSize.Bytes() {
+		return true
+	}
+
+	return o.c.MaxAge > 0 && o.now().Sub(o.opened) >= o.c.MaxAge
+}
+
+// Sync commits the written data to the disk.
+func (o *RotatingFile) Sync() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.f == nil {
+		return nil
+	}
+
+	return o.f.Sync()
+}
+
+// Rotate rotates the file regardless of its size and age.
+func (o *RotatingFile) Rotate() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return o.rotate()
+}
+
+// Reopen closes the file and opens it by its path again, so the file renamed
+// by an external tool such as logrotate is no longer written to.
+func (o *RotatingFile) Reopen() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if err := o.close(); err != nil {
+		return err
+	}
+
+	return o.open()
+}
+
+// Close closes the file and waits for the background compression and cleanup.
+func (o *RotatingFile) Close() error {
+	o.mu.Lock()
+	err := o.close()
+	o.mu.Unlock()
+
+	o.wg.Wait()
+
+	return err
+}
+
+func (o *RotatingFile) open() error {
+	f, err := os.OpenFile(o.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("open log file error: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return fmt.Errorf("stat log file error: %w", err)
+	}
+
+	o.f, o.size, o.opened = f, info.Size(), o.now()
+
+	return nil
+}
+
+func (o *RotatingFile) close() error {
+	if o.f == nil {
+		return nil
+	}
+	err := o.f.Close()
+	o.f = nil
+	if err != nil {
+		return fmt.Errorf("close log file error: %w", err)
+	}
+
+	return nil
+}
+
+func (o *RotatingFile) rotate() error {
+	if err := o.close(); err != nil {
+		return err
+	}
+
+	ext := filepath.Ext(o.path)
+	backup := fmt.Sprintf("%s-%s%s", strings.TrimSuffix(o.path, ext), o.now().UTC().Format(backupTimeFormat), ext)
+	if err := os.Rename(o.path, backup); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("rename log file error: %w", err)
+	}
+	if err := o.open(); err != nil {
+		return err
+	}
+
+	o.wg.Add(1)
+	go func() {
+		defer o.wg.Done()
+		o.cleanup()
+	}()
+
+	return nil
+}
+
+// cleanup compresses the rotated files and removes the ones beyond MaxBackups.
+func (o *RotatingFile) cleanup() {
+	o.cleanupMu.Lock()
+	defer o.cleanupMu.Unlock()
+
+	backups := o.backups()
+	if o.c.Compress {
+		for i, b := range backups {
+			if strings.HasSuffix(b, ".gz") {
+				continue
+			}
+			if err := compress(b); err != nil {
+				_, _ = fmt.Fprintf(os.Stderr, "log rotation: %v\n", err)
+				continue
+			}
+			backups[i] = b + ".gz"
+		}
+	}
+
+	if o.c.MaxBackups > 0 && len(backups) > o.c.MaxBackups {
+		for _, b := range backups[o.c.MaxBackups:] {
+			if err := os.Remove(b); err != nil {
+				_, _ = fmt.Fprintf(os.Stderr, "log rotation: remove %s error: %v\n", b, err)
+			}
+		}
+	}
+}
+
+// backups returns the paths of the rotated files, the newest first.
+func (o *RotatingFile) backups() []string {
+	ext := filepath.Ext(o.path)
+	prefix := filepath.Base(strings.TrimSuffix(o.path, ext)) + "-"
+
+	entries, err := os.ReadDir(filepath.Dir(o.path))
+	if err != nil {
+		return nil
+	}
+
+	var res []string
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		stamp := strings.TrimSuffix(strings.TrimSuffix(strings.TrimPrefix(name, prefix), ".gz"), ext)
+		if _, err := time.Parse(backupTimeFormat, stamp); err != nil {
+			continue
+		}
+		res = append(res, filepath.Join(filepath.Dir(o.path), name))
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(res)))
+
+	return res
+}
+
+func compress(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("open %s error: %w", path, err)
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(path+".gz", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return fmt.Errorf("create %s.gz error: %w", path, err)
+	}
+
+	zw := gzip.NewWriter(dst)
+	if _, err = io.Copy(zw, src); err == nil {
+		err = zw.Close()
+	}
+	if cerr := dst.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		_ = os.Remove(path + ".gz")
+		return fmt.Errorf("compress %s error: %w", path, err)
+	}
+
+	return os.Remove(path)
+}
+
+// isFile reports whether the output path is a file rather than a standard stream or another zap sink.
+func isFile(path string) bool {
+	if path == "stdout" || path == "stderr" {
+		return false
+	}
+
+	return !strings.Contains(path, "://") || strings.HasPrefix(path, "file://")
+}
+
+// Reopen reopens the file outputs of the logger (see RotatingFile.Reopen), they are opened
+// as RotatingFile whether the rotation is enabled or not.
+func (o *Zap) Reopen() error {
+	for _, f := range o.files {
+		if err := f.Reopen(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ReopenOnSignal returns an actor for grace.RunGroup.Add that reopens the file outputs on every
+// signal sent by the postrotate script of logrotate, SIGHUP if no signals are given.
+// grace.RunGroup shuts down on SIGHUP by default, so leave it out of the shutdown signals:
+//
+//	g := grace.NewRunGroup().ShutdownSignals(syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
+//	g.Add(logger.ReopenOnSignal())
+//
+// with `postrotate kill -HUP $(cat /run/app.pid) endscript` in the logrotate config.
+func (o *Zap) ReopenOnSignal(sig ...os.Signal) (func() error, func(error)) {
+	if len(sig) == 0 {
+		sig = []os.Signal{syscall.SIGHUP}
+	}
+
+	sigs := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(sigs, sig...)
+
+	exec := func() error {
+		defer signal.Stop(sigs)
+
+		for {
+			select {
+			case <-sigs:
+				if err := o.Reopen(); err != nil {
+					o.Error("reopen log files error", zap.Error(err))
+				}
+			case <-done:
+				return nil
+			}
+		}
+	}
+	interrupt := func(error) {
+		close(done)
+	}
+
+	return exec, interrupt
+}
@@ -0,0 +1,173 @@
+package log
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/sorohimm/utils/cfg/cfgtest"
+	"github.com/sorohimm/utils/cfg/types"
+)
+
+func dirFiles(t *testing.T, dir string) []string {
+	t.Helper()
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+func TestRotatingFileSize(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	f, err := NewRotatingFile(filepath.Join(dir, "app.log"), RotationConfig{MaxSize: 10, MaxBackups: 2})
+	require.NoError(t, err)
+	f.now = func() time.Time { return now }
+
+	for _, line := range []string{"first\n", "second\n", "third\n", "fourth\n"} {
+		_, err = f.Write([]byte(line))
+		require.NoError(t, err)
+		now = now.Add(time.Second)
+	}
+	require.NoError(t, f.Close())
+
+	require.Equal(t, []string{
+		"app-2024-01-02T03-04-07.000.log",
+		"app-2024-01-02T03-04-08.000.log",
+		"app.log",
+	}, dirFiles(t, dir))
+
+	data, err := os.ReadFile(filepath.Join(dir, "app.log"))
+	require.NoError(t, err)
+	require.Equal(t, "fourth\n", string(data))
+	data, err = os.ReadFile(filepath.Join(dir, "app-2024-01-02T03-04-08.000.log"))
+	require.NoError(t, err)
+	require.Equal(t, "third\n", string(data))
+}
+
+func TestRotatingFileAgeCompress(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	f, err := NewRotatingFile(filepath.Join(dir, "app.log"), RotationConfig{MaxAge: time.Hour, Compress: true})
+	require.NoError(t, err)
+	f.now = func() time.Time { return now }
+	f.opened = now
+
+	_, err = f.Write([]byte("old\n"))
+	require.NoError(t, err)
+	now = now.Add(time.Hour)
+	_, err = f.Write([]byte("new\n"))
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	require.Equal(t, []string{"app-2024-01-02T04-04-05.000.log.gz", "app.log"}, dirFiles(t, dir))
+
+	gz, err := os.Open(filepath.Join(dir, "app-2024-01-02T04-04-05.000.log.gz"))
+	require.NoError(t, err)
+	defer gz.Close()
+	zr, err := gzip.NewReader(gz)
+	require.NoError(t, err)
+	data, err := io.ReadAll(zr)
+	require.NoError(t, err)
+	require.Equal(t, "old\n", string(data))
+}
+
+func TestRotatingOutputs(t *testing.T) {
+	t.Run("rotation", func(t *testing.T) {
+		testReopen(t, RotationConfig{MaxSize: 1 << 20})
+	})
+	t.Run("noRotation", func(t *testing.T) {
+		testReopen(t, RotationConfig{})
+	})
+}
+
+func testReopen(t *testing.T, c RotationConfig) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+
+	logger, err := New(Config{
+		OutputPaths: []string{"stderr", path},
+		Rotation:    c,
+	})
+	require.NoError(t, err)
+	require.Len(t, logger.files, 1)
+
+	logger.Info("before")
+	// logrotate renames the file and signals the service to reopen it
+	require.NoError(t, os.Rename(path, path+".1"))
+	require.NoError(t, logger.Reopen())
+	logger.Info("after")
+
+	data, err := os.ReadFile(path + ".1")
+	require.NoError(t, err)
+	require.Contains(t, string(data), `"msg":"before"`)
+	data, err = os.ReadFile(path)
+	require.NoError(t, err)
+	require.Contains(t, string(data), `"msg":"after"`)
+	require.False(t, strings.Contains(string(data), "before"))
+}
+
+func TestReopenOnSignal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	logger, err := New(Config{OutputPaths: []string{path}})
+	require.NoError(t, err)
+
+	exec, interrupt := logger.ReopenOnSignal()
+	done := make(chan error)
+	go func() {
+		done <- exec()
+	}()
+
+	logger.Info("before")
+	require.NoError(t, os.Rename(path, path+".1"))
+	require.NoError(t, syscall.Kill(syscall.Getpid(), syscall.SIGHUP))
+	require.Eventually(t, func() bool {
+		_, err := os.Stat(path)
+		return err == nil
+	}, time.Second, 10*time.Millisecond)
+	logger.Info("after")
+
+	interrupt(nil)
+	require.NoError(t, <-done)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Contains(t, string(data), `"msg":"after"`)
+	require.NotContains(t, string(data), "before")
+}
+
+func TestIsFile(t *testing.T) {
+	require.True(t, isFile("/var/log/app.log"))
+	require.True(t, isFile("file:///var/log/app.log"))
+	require.False(t, isFile("stdout"))
+	require.False(t, isFile("memory://sink"))
+}
+
+func TestRotationConfig(t *testing.T) {
+	c := serviceCfg{}
+	cfgtest.Load(t, &c, `
+		log:
+		  rotation:
+		    maxSize: 100MB
+		    maxAge: 24h
+		    compress: true
+	`, map[string]string{"LOG_ROTATION_MAX_BACKUPS": "7"})
+
+	require.Equal(t, RotationConfig{MaxSize: 100 * types.MB, MaxAge: 24 * time.Hour, MaxBackups: 7, Compress: true}, c.Log.Rotation)
+}
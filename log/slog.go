@@ -0,0 +1,310 @@
+package log
+
+import (
+	"context"
+	"log/slog"
+	"runtime"
+	"sort"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// NewSlogHandler returns an slog.Handler writing to the logger,
+// so libraries using log/slog log through the service logger.
+// Groups become nested objects, attrs are resolved (see slog.LogValuer) and levels are mapped
+// to the closest zap level at or below them (e.g. slog.LevelWarn+2 is zap warn).
+func NewSlogHandler(logger *Zap) slog.Handler {
+	return &slogHandler{core: logger.Core(), name: logger.Name()}
+}
+
+type slogHandler struct {
+	core   zapcore.Core
+	name   string
+	groups []string // groups opened with WithGroup and not yet written as namespaces
+}
+
+func (h *slogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return h.core.Enabled(zapLevel(level))
+}
+
+func (h *slogHandler) Handle(_ context.Context, r slog.Record) error {
+	ent := zapcore.Entry{
+		Level:      zapLevel(r.Level),
+		Time:       r.Time,
+		LoggerName: h.name,
+		Message:    r.Message,
+	}
+	if r.PC != 0 {
+		frame, _ := runtime.CallersFrames([]uintptr{r.PC}).Next()
+		ent.Caller = zapcore.NewEntryCaller(r.PC, frame.File, frame.Line, true)
+		ent.Caller.Function = frame.Function
+	}
+
+	ce := h.core.Check(ent, nil)
+	if ce == nil {
+		return nil
+	}
+
+	fields := make([]zap.Field, 0, r.NumAttrs()+len(h.groups))
+	r.Attrs(func(a slog.Attr) bool {
+		fields = appendAttr(fields, a)
+		return true
+	})
+	if len(fields) > 0 {
+		fields = append(namespaces(h.groups), fields...)
+	}
+	ce.Write(fields...)
+
+	return nil
+}
+
+func (h *slogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	fields := make([]zap.Field, 0, len(attrs))
+	for _, a := range attrs {
+		fields = appendAttr(fields, a)
+	}
+	if len(fields) == 0 {
+		return h
+	}
+
+	return &slogHandler{
+		core: h.core.With(append(namespaces(h.groups), fields...)),
+		name: h.name,
+	}
+}
+
+func (h *slogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+
+	return &slogHandler{
+		core:   h.core,
+		name:   h.name,
+		groups: append(h.groups[:len(h.groups):len(h.groups)], name),
+	}
+}
+
+func namespaces(groups []string) []zap.Field {
+	fields := make([]zap.Field, 0, len(groups))
+	for _, g := range groups {
+		fields = append(fields, zap.Namespace(g))
+	}
+
+	return fields
+}
+
+func appendAttr(fields []zap.Field, a slog.Attr) []zap.Field {
+	a.Value = a.Value.Resolve()
+	if a.Key == "" && a.Value.Kind() == slog.KindAny && a.Value.Any() == nil {
+		return fields
+	}
+
+	switch a.Value.Kind() {
+	case slog.KindString:
+		return append(fields, zap.String(a.Key, a.Value.String()))
+	case slog.KindInt64:
+		return append(fields, zap.Int64(a.Key, a.Value.Int64()))
+	case slog.KindUint64:
+		return append(fields, zap.Uint64(a.Key, a.Value.Uint64()))
+	case slog.KindFloat64:
+		return append(fields, zap.Float64(a.Key, a.Value.Float64()))
+	case slog.KindBool:
+		return append(fields, zap.Bool(a.Key, a.Value.Bool()))
+	case slog.KindDuration:
+		return append(fields, zap.Duration(a.Key, a.Value.Duration()))
+	case slog.KindTime:
+		return append(fields, zap.Time(a.Key, a.Value.Time()))
+	case slog.KindGroup:
+		attrs := a.Value.Group()
+		if len(attrs) == 0 {
+			return fields
+		}
+		if a.Key == "" {
+			for _, ga := range attrs {
+				fields = appendAttr(fields, ga)
+			}
+			return fields
+		}
+		return append(fields, zap.Object(a.Key, groupMarshaler(attrs)))
+	}
+
+	if err, ok := a.Value.Any().(error); ok {
+		return append(fields, zap.NamedError(a.Key, err))
+	}
+
+	return append(fields, zap.Any(a.Key, a.Value.Any()))
+}
+
+type groupMarshaler []slog.Attr
+
+func (g groupMarshaler) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	var fields []zap.Field
+	for _, a := range g {
+		fields = appendAttr(fields, a)
+	}
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+
+	return nil
+}
+
+func slogAny(attrs []slog.Attr) []any {
+	res := make([]any, 0, len(attrs))
+	for _, a := range attrs {
+		res = append(res, a)
+	}
+
+	return res
+}
+
+// zapLevel maps the slog level to the closest zap level at or below it.
+func zapLevel(l slog.Level) zapcore.Level {
+	switch {
+	case l < slog.LevelInfo:
+		return zapcore.DebugLevel
+	case l < slog.LevelWarn:
+		return zapcore.InfoLevel
+	case l < slog.LevelError:
+		return zapcore.WarnLevel
+	}
+
+	return zapcore.ErrorLevel
+}
+
+func slogLevel(l zapcore.Level) slog.Level {
+	switch {
+	case l <= zapcore.DebugLevel:
+		return slog.LevelDebug
+	case l == zapcore.InfoLevel:
+		return slog.LevelInfo
+	case l == zapcore.WarnLevel:
+		return slog.LevelWarn
+	}
+
+	return slog.LevelError
+}
+
+// NewFromSlog returns a logger writing to the slog.Handler, the handler decides which levels are enabled.
+// Fields are converted to attrs, namespaces become groups.
+func NewFromSlog(h slog.Handler) *Zap {
+	if zh, ok := h.(*slogHandler); ok && len(zh.groups) == 0 {
+		return &Zap{Logger: zap.New(zh.core).Named(zh.name), level: zap.NewAtomicLevelAt(zapcore.DebugLevel)}
+	}
+
+	return &Zap{Logger: zap.New(&slogCore{h: h}), level: zap.NewAtomicLevelAt(zapcore.DebugLevel)}
+}
+
+// slogCore is a zapcore.Core writing to an slog.Handler.
+type slogCore struct {
+	h slog.Handler
+}
+
+func (c *slogCore) Enabled(level zapcore.Level) bool {
+	return c.h.Enabled(context.Background(), slogLevel(level))
+}
+
+func (c *slogCore) With(fields []zapcore.Field) zapcore.Core {
+	h := c.h
+	for i, seg := range segments(fields) {
+		if i > 0 {
+			h = h.WithGroup(seg.group)
+		}
+		if len(seg.attrs) > 0 {
+			h = h.WithAttrs(seg.attrs)
+		}
+	}
+
+	return &slogCore{h: h}
+}
+
+func (c *slogCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+
+	return ce
+}
+
+func (c *slogCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	r := slog.NewRecord(ent.Time, slogLevel(ent.Level), ent.Message, ent.Caller.PC)
+	if ent.LoggerName != "" {
+		r.AddAttrs(slog.String("logger", ent.LoggerName))
+	}
+
+	// fields after a namespace belong to it, so the segments are nested from the innermost one
+	segs := segments(fields)
+	var attrs []slog.Attr
+	for i := len(segs) - 1; i > 0; i-- {
+		attrs = []slog.Attr{slog.Group(segs[i].group, slogAny(append(segs[i].attrs, attrs...))...)}
+	}
+	r.AddAttrs(append(segs[0].attrs, attrs...)...)
+
+	return c.h.Handle(context.Background(), r)
+}
+
+func (c *slogCore) Sync() error {
+	return nil
+}
+
+type segment struct {
+	group string
+	attrs []slog.Attr
+}
+
+// segments splits the fields by namespaces and converts them to attrs.
+// The first segment holds the fields before any namespace and has no group.
+func segments(fields []zapcore.Field) []segment {
+	segs := []segment{{}}
+	enc := zapcore.NewMapObjectEncoder()
+	for _, f := range fields {
+		if f.Type == zapcore.NamespaceType {
+			segs[len(segs)-1].attrs = mapAttrs(enc.Fields)
+			segs = append(segs, segment{group: f.Key})
+			enc = zapcore.NewMapObjectEncoder()
+			continue
+		}
+		f.AddTo(enc)
+	}
+	segs[len(segs)-1].attrs = mapAttrs(enc.Fields)
+
+	return segs
+}
+
+func mapAttrs(m map[string]interface{}) []slog.Attr {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	attrs := make([]slog.Attr, 0, len(keys))
+	for _, k := range keys {
+		if nested, ok := m[k].(map[string]interface{}); ok {
+			attrs = append(attrs, slog.Group(k, slogAny(mapAttrs(nested))...))
+			continue
+		}
+		attrs = append(attrs, slog.Any(k, m[k]))
+	}
+
+	return attrs
+}
+
+// CtxWithSlog adds the slog logger to the context as the context logger,
+// so FromContext and SlogFromContext return the same logger.
+func CtxWithSlog(ctx context.Context, logger *slog.Logger) context.Context {
+	return CtxWithLogger(ctx, NewFromSlog(logger.Handler()))
+}
+
+// SlogFromContext returns the context logger as an slog logger, a logger discarding
+// everything if the context has none (see FromContext).
+func SlogFromContext(ctx context.Context) *slog.Logger {
+	logger := FromContext(ctx)
+	if c, ok := logger.Core().(*slogCore); ok {
+		return slog.New(c.h)
+	}
+
+	return slog.New(NewSlogHandler(logger))
+}
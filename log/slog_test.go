@@ -0,0 +1,103 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/sorohimm/utils/cfg/types"
+)
+
+type token string
+
+func (token) LogValue() slog.Value {
+	return slog.StringValue("***")
+}
+
+func TestSlogHandler(t *testing.T) {
+	logger, lines := newFileZap(t, Config{Level: types.LogLevel(zapcore.InfoLevel), DisableCaller: true})
+
+	l := slog.New(NewSlogHandler(logger))
+	l.Debug("debug")
+	l.With("service", "api").WithGroup("req").Info("served",
+		slog.Int("status", 200),
+		slog.Group("user", slog.String("id", "42"), slog.Any("token", token("secret"))),
+	)
+	l.WithGroup("empty").Warn("warn")
+	l.Log(context.Background(), slog.LevelWarn+2, "between", "err", errors.New("boom"))
+
+	got := lines()
+	require.Len(t, got, 3)
+
+	var entry map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(got[0]), &entry))
+	require.Equal(t, "INFO", entry["level"])
+	require.Equal(t, "api", entry["service"])
+	require.Equal(t, map[string]interface{}{
+		"status": float64(200),
+		"user":   map[string]interface{}{"id": "42", "token": "***"},
+	}, entry["req"])
+
+	require.Contains(t, got[1], `"msg":"warn"`)
+	require.NotContains(t, got[1], "empty")
+
+	require.Contains(t, got[2], `"level":"WARN"`)
+	require.Contains(t, got[2], `"err":"boom"`)
+}
+
+func TestNewFromSlog(t *testing.T) {
+	var buf bytes.Buffer
+	h := slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelInfo})
+
+	logger := NewFromSlog(h)
+	logger.Debug("debug")
+	logger.With(zap.String("service", "api"), zap.Namespace("req")).
+		Info("served", zap.Int("status", 200), zap.Namespace("user"), zap.String("id", "42"))
+	logger.Named("db").Warn("warn")
+
+	got := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, got, 2)
+
+	var entry map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(got[0]), &entry))
+	require.Equal(t, "INFO", entry["level"])
+	require.Equal(t, "api", entry["service"])
+	require.Equal(t, map[string]interface{}{
+		"status": float64(200),
+		"user":   map[string]interface{}{"id": "42"},
+	}, entry["req"])
+
+	require.Contains(t, got[1], `"level":"WARN"`)
+	require.Contains(t, got[1], `"logger":"db"`)
+}
+
+func TestSlogContext(t *testing.T) {
+	logger, lines := newFileZap(t, Config{DisableCaller: true})
+	ctx := CtxWithLogger(context.Background(), logger.WithRequestId("r1"))
+
+	SlogFromContext(ctx).Info("from slog")
+	FromContext(ctx).Info("from zap")
+
+	got := lines()
+	require.Len(t, got, 2)
+	require.Contains(t, got[0], `"reqId":"r1"`)
+	require.Contains(t, got[1], `"reqId":"r1"`)
+
+	var buf bytes.Buffer
+	sl := slog.New(slog.NewJSONHandler(&buf, nil)).With("request_id", "r2")
+	ctx = CtxWithSlog(context.Background(), sl)
+
+	FromContext(ctx).Info("from zap")
+	require.Same(t, sl.Handler(), SlogFromContext(ctx).Handler())
+	require.Contains(t, buf.String(), `"request_id":"r2"`)
+
+	SlogFromContext(context.Background()).Error("discarded")
+}
@@ -0,0 +1,129 @@
+package log
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// WithTraceContext returns a logger FromContext adds the TraceId and SpanId fields of the context span to,
+// so log lines link to traces:
+//
+//	ctx = log.CtxWithLogger(ctx, logger.WithTraceContext())
+//	ctx, span := tracer.Start(ctx, "op")
+//	log.FromContext(ctx).Info("done") // {"msg":"done","trace_id":"...","span_id":"..."}
+func (o *Zap) WithTraceContext() *Zap {
+	c := o.with(o.Logger)
+	c.trace = true
+
+	return c
+}
+
+// WithSpanEvents returns a logger FromContext records the error level entries of as events
+// of the context span, with the entry fields as event attributes. The events are redacted
+// the way the entries are (see RedactConfig).
+func (o *Zap) WithSpanEvents() *Zap {
+	c := o.with(o.Logger)
+	c.spanEvents = true
+
+	return c
+}
+
+func traceFields(ctx context.Context) []zap.Field {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return nil
+	}
+
+	return []zap.Field{zap.String(TraceId, sc.TraceID().String()), zap.String(SpanId, sc.SpanID().String())}
+}
+
+// withSpan returns the logger recording its error entries as events of the context span.
+// The span core is placed under the component core, so it is filtered by the component level
+// and a component logger derived from the result still replaces the component core (see withComponent).
+// The span core of a previous span is replaced.
+func (o *Zap) withSpan(ctx context.Context) *Zap {
+	span := trace.SpanFromContext(ctx)
+	if !span.IsRecording() || span == o.span {
+		return o
+	}
+
+	c := o.derive(func(l *zap.Logger) *zap.Logger {
+		return l.WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+			if c, ok := core.(*componentCore); ok {
+				return &componentCore{Core: withSpanCore(c.Core, span, o.redact), levels: c.levels, component: c.component}
+			}
+
+			return withSpanCore(core, span, o.redact)
+		}))
+	})
+	c.span = span
+
+	return c
+}
+
+func withSpanCore(core zapcore.Core, span trace.Span, r *redactor) zapcore.Core {
+	if c, ok := core.(*spanCore); ok {
+		return &spanCore{Core: c.Core, span: span, fields: c.fields, r: r}
+	}
+
+	return &spanCore{Core: core, span: span, r: r}
+}
+
+// spanCore adds the error level entries of the wrapped core as span events.
+// It is placed above the redaction core, so it redacts the events with r on its own.
+type spanCore struct {
+	zapcore.Core
+	span   trace.Span
+	fields []zapcore.Field
+	r      *redactor
+}
+
+func (c *spanCore) With(fields []zapcore.Field) zapcore.Core {
+	return &spanCore{
+		Core:   c.Core.With(fields),
+		span:   c.span,
+		fields: append(c.fields[:len(c.fields):len(c.fields)], fields...),
+		r:      c.r,
+	}
+}
+
+func (c *spanCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	ce = c.Core.Check(ent, ce)
+	if ent.Level >= zapcore.ErrorLevel && c.span.IsRecording() {
+		ce = ce.AddCore(ent, c)
+	}
+
+	return ce
+}
+
+// Write adds the entry as a span event, the wrapped core writes it on its own as it is added
+// to the checked entry by its Check.
+func (c *spanCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	prefix := c.fields
+	if c.r != nil {
+		ent.Message = c.r.string(ent.Message)
+		prefix, fields = c.r.fieldsOf(prefix), c.r.fieldsOf(fields)
+	}
+
+	enc := zapcore.NewMapObjectEncoder()
+	for _, f := range prefix {
+		f.AddTo(enc)
+	}
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+
+	attrs := make([]attribute.KeyValue, 0, len(enc.Fields)+1)
+	attrs = append(attrs, attribute.String("level", ent.Level.String()))
+	for k, v := range enc.Fields {
+		attrs = append(attrs, attribute.String(k, fmt.Sprint(v)))
+	}
+	c.span.AddEvent(ent.Message, trace.WithTimestamp(ent.Time), trace.WithAttributes(attrs...))
+
+	return nil
+}
@@ -0,0 +1,103 @@
+package log
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace/noop"
+	"go.uber.org/zap"
+)
+
+func TestTraceContext(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tracer := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)).Tracer("test")
+
+	logger, lines := newFileZap(t, Config{DisableCaller: true, DisableStacktrace: true, Levels: map[string]string{"db": "warn"}})
+	ctx := CtxWithLogger(context.Background(), logger.WithTraceContext().WithSpanEvents())
+
+	ctx, span := tracer.Start(ctx, "op")
+	FromContext(ctx).Info("info")
+	FromContext(WithUserId(ctx, "u1")).Error("failed", zap.String("key", "value"))
+
+	// the logger put back into the context is not enriched twice
+	reqCtx := CtxWithLogger(ctx, FromContext(ctx).WithRequestId("r1"))
+	FromContext(reqCtx).Error("request failed")
+	FromContext(reqCtx).WithSource("db").Error("db failed")
+	FromContext(reqCtx).WithSource("db").Info("db info")
+
+	childCtx, child := tracer.Start(CtxWithLogger(reqCtx, FromContext(reqCtx)), "child")
+	FromContext(childCtx).Error("child failed")
+	child.End()
+	span.End()
+
+	sc := span.SpanContext()
+	got := lines()
+	require.Len(t, got, 5)
+	for _, line := range got[:4] {
+		require.Contains(t, line, `"trace_id":"`+sc.TraceID().String()+`","span_id":"`+sc.SpanID().String()+`"`)
+		require.Equal(t, 1, strings.Count(line, TraceId))
+		require.Equal(t, 1, strings.Count(line, SpanId))
+	}
+	// the span fields of the parent span logger are replaced by the child span ones
+	require.Contains(t, got[4], `"span_id":"`+child.SpanContext().SpanID().String()+`"`)
+	require.Equal(t, 1, strings.Count(got[4], SpanId))
+	require.Equal(t, 1, strings.Count(got[4], TraceId))
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 2)
+	require.Equal(t, "child", spans[0].Name)
+	require.Len(t, spans[0].Events, 1)
+	require.Equal(t, "child failed", spans[0].Events[0].Name)
+
+	events := spans[1].Events
+	require.Len(t, events, 3)
+	require.Equal(t, "failed", events[0].Name)
+	require.Contains(t, events[0].Attributes, attribute.String("level", "error"))
+	require.Contains(t, events[0].Attributes, attribute.String("key", "value"))
+	require.Contains(t, events[0].Attributes, attribute.String(UserId, "u1"))
+	require.Equal(t, "request failed", events[1].Name)
+	require.Equal(t, "db failed", events[2].Name)
+}
+
+func TestTraceRedact(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tracer := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)).Tracer("test")
+
+	logger, lines := newFileZap(t, Config{DisableCaller: true, DisableStacktrace: true, Redact: RedactConfig{Enabled: true}})
+	ctx := CtxWithLogger(context.Background(), logger.WithSpanEvents())
+
+	ctx, span := tracer.Start(ctx, "op")
+	FromContext(ctx).With(zap.String("token", "tok-123")).
+		Error("connect postgres://app:hunter2@db failed", zap.String("password", "hunter2"))
+	span.End()
+
+	got := lines()
+	require.Len(t, got, 1)
+	require.NotContains(t, got[0], "hunter2")
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 1)
+	require.Len(t, spans[0].Events, 1)
+	event := spans[0].Events[0]
+	require.Equal(t, "connect postgres://app:***@db failed", event.Name)
+	require.Contains(t, event.Attributes, attribute.String("password", Mask))
+	require.Contains(t, event.Attributes, attribute.String("token", Mask))
+}
+
+func TestTraceContextNoop(t *testing.T) {
+	logger, lines := newFileZap(t, Config{DisableCaller: true})
+	ctx := CtxWithLogger(context.Background(), logger.WithTraceContext().WithSpanEvents())
+
+	ctx, span := noop.NewTracerProvider().Tracer("test").Start(ctx, "op")
+	FromContext(ctx).Error("failed")
+	span.End()
+
+	got := lines()
+	require.Len(t, got, 1)
+	require.NotContains(t, got[0], TraceId)
+}